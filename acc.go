@@ -0,0 +1,289 @@
+package gosplice
+
+import (
+	"errors"
+	"math"
+)
+
+// Accumulators are the single-pass building blocks of the statistics
+// functions. Each has Add, Merge and Result; the zero value is ready to use
+// (except HistogramAcc, which needs its bins up front). Merge combines
+// partial results from parallel workers, shards or earlier runs — merging
+// partials gives the same result as one pass over all values, up to
+// floating-point rounding. All fields are exported, so accumulators
+// serialise with encoding/json and encoding/gob as they are.
+//
+// NaN is handled as in DescribeBy, whatever its position in the input: it
+// is counted and makes every sum, mean and (co)variance it enters NaN,
+// while MinMaxAcc ignores it and HistogramAcc counts it apart, in NaN.
+//
+// Accumulators are not safe for concurrent use; give each worker its own.
+
+// MeanAcc accumulates an arithmetic mean.
+type MeanAcc struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+func (a *MeanAcc) Add(x float64) {
+	a.Sum += x
+	a.Count++
+}
+
+func (a *MeanAcc) Merge(other *MeanAcc) {
+	a.Sum += other.Sum
+	a.Count += other.Count
+}
+
+// Result returns the mean, or 0 if empty.
+func (a *MeanAcc) Result() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.Sum / float64(a.Count)
+}
+
+// VarianceAcc accumulates mean and variance with Welford's online algorithm.
+type VarianceAcc struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"` // sum of squared deviations from Mean
+}
+
+func (a *VarianceAcc) Add(x float64) {
+	a.Count++
+	delta := x - a.Mean
+	a.Mean += delta / float64(a.Count)
+	a.M2 += delta * (x - a.Mean)
+}
+
+// Merge uses the parallel combination of Chan, Golub and LeVeque.
+func (a *VarianceAcc) Merge(other *VarianceAcc) {
+	if other.Count == 0 {
+		return
+	}
+	if a.Count == 0 {
+		*a = *other
+		return
+	}
+	n := float64(a.Count + other.Count)
+	delta := other.Mean - a.Mean
+	a.Mean += delta * float64(other.Count) / n
+	a.M2 += other.M2 + delta*delta*float64(a.Count)*float64(other.Count)/n
+	a.Count += other.Count
+}
+
+// Result returns the population variance (like VarianceBy), or 0 if empty.
+func (a *VarianceAcc) Result() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.M2 / float64(a.Count)
+}
+
+func (a *VarianceAcc) StdDev() float64 { return math.Sqrt(a.Result()) }
+
+// MinMaxAcc tracks the smallest and largest value. NaN is ignored and not
+// counted.
+type MinMaxAcc struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+func (a *MinMaxAcc) Add(x float64) {
+	if math.IsNaN(x) {
+		return
+	}
+	if a.Count == 0 || x < a.Min {
+		a.Min = x
+	}
+	if a.Count == 0 || x > a.Max {
+		a.Max = x
+	}
+	a.Count++
+}
+
+func (a *MinMaxAcc) Merge(other *MinMaxAcc) {
+	if other.Count == 0 {
+		return
+	}
+	if a.Count == 0 {
+		*a = *other
+		return
+	}
+	a.Min = math.Min(a.Min, other.Min)
+	a.Max = math.Max(a.Max, other.Max)
+	a.Count += other.Count
+}
+
+// Result returns (min, max), or (0, 0) if empty.
+func (a *MinMaxAcc) Result() (float64, float64) { return a.Min, a.Max }
+
+// CorrelationAcc accumulates the Pearson correlation of (x, y) pairs with
+// online covariance.
+type CorrelationAcc struct {
+	Count    int     `json:"count"`
+	MeanX    float64 `json:"mean_x"`
+	MeanY    float64 `json:"mean_y"`
+	M2X      float64 `json:"m2_x"`
+	M2Y      float64 `json:"m2_y"`
+	CoMoment float64 `json:"co_moment"` // sum of (x-MeanX)(y-MeanY)
+}
+
+func (a *CorrelationAcc) Add(x, y float64) {
+	a.Count++
+	n := float64(a.Count)
+	dx := x - a.MeanX
+	a.MeanX += dx / n
+	a.M2X += dx * (x - a.MeanX)
+
+	dy := y - a.MeanY
+	a.MeanY += dy / n
+	a.M2Y += dy * (y - a.MeanY)
+
+	a.CoMoment += dx * (y - a.MeanY)
+}
+
+func (a *CorrelationAcc) Merge(other *CorrelationAcc) {
+	if other.Count == 0 {
+		return
+	}
+	if a.Count == 0 {
+		*a = *other
+		return
+	}
+	na, nb := float64(a.Count), float64(other.Count)
+	n := na + nb
+	dx := other.MeanX - a.MeanX
+	dy := other.MeanY - a.MeanY
+	a.M2X += other.M2X + dx*dx*na*nb/n
+	a.M2Y += other.M2Y + dy*dy*na*nb/n
+	a.CoMoment += other.CoMoment + dx*dy*na*nb/n
+	a.MeanX += dx * nb / n
+	a.MeanY += dy * nb / n
+	a.Count += other.Count
+}
+
+// Result returns the Pearson correlation, or 0 for fewer than 2 pairs or
+// zero variance (like CorrelationBy).
+func (a *CorrelationAcc) Result() float64 {
+	if a.Count < 2 || a.M2X == 0 || a.M2Y == 0 {
+		return 0
+	}
+	return a.CoMoment / math.Sqrt(a.M2X*a.M2Y)
+}
+
+// Covariance returns the population covariance.
+func (a *CorrelationAcc) Covariance() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.CoMoment / float64(a.Count)
+}
+
+// HistogramAcc counts values into equal-width bins over a fixed [Lo, Hi]
+// range. Unlike Histogram it cannot look at the data first, so the range is
+// given up front; values outside it are counted in Under and Over, and NaN
+// in NaN.
+type HistogramAcc struct {
+	Lo     float64 `json:"lo"`
+	Hi     float64 `json:"hi"`
+	Counts []int   `json:"counts"`
+	Under  int     `json:"under"`
+	Over   int     `json:"over"`
+	NaN    int     `json:"nan"`
+}
+
+// NewHistogramAcc returns a histogram with bins equal-width buckets over [lo, hi].
+func NewHistogramAcc(lo, hi float64, bins int) *HistogramAcc {
+	return &HistogramAcc{Lo: lo, Hi: hi, Counts: make([]int, max(bins, 1))}
+}
+
+func (a *HistogramAcc) Add(x float64) {
+	switch {
+	case math.IsNaN(x):
+		a.NaN++
+	case x < a.Lo:
+		a.Under++
+	case x > a.Hi:
+		a.Over++
+	case a.Hi == a.Lo:
+		a.Counts[0]++
+	default:
+		bins := len(a.Counts)
+		idx := min(int((x-a.Lo)/(a.Hi-a.Lo)*float64(bins)), bins-1)
+		a.Counts[idx]++
+	}
+}
+
+var errHistogramMismatch = errors.New("gosplice: cannot merge histograms with different bins")
+
+// Merge adds other's counts. Both must have the same range and number of
+// bins; otherwise a is left unchanged and an error is returned, as other
+// may have been loaded from stored data.
+func (a *HistogramAcc) Merge(other *HistogramAcc) error {
+	if a.Lo != other.Lo || a.Hi != other.Hi || len(a.Counts) != len(other.Counts) {
+		return errHistogramMismatch
+	}
+	for i, c := range other.Counts {
+		a.Counts[i] += c
+	}
+	a.Under += other.Under
+	a.Over += other.Over
+	a.NaN += other.NaN
+	return nil
+}
+
+// Result returns edges (bins+1 boundaries) and counts, like Histogram.
+func (a *HistogramAcc) Result() (edges []float64, counts []int) {
+	bins := len(a.Counts)
+	edges = make([]float64, bins+1)
+	width := (a.Hi - a.Lo) / float64(bins)
+	for i := range edges {
+		edges[i] = a.Lo + float64(i)*width
+	}
+	edges[bins] = a.Hi
+	return edges, append([]int(nil), a.Counts...)
+}
+
+// ParallelDescribeBy is DescribeBy computed across workers: the input is
+// drained into memory (like PipeMapParallel), each worker builds a partial
+// StatsAcc over its share and the partials are merged. Use it when fn is
+// expensive. Quartiles come from merged sketches and are approximate
+// (see StatsAcc).
+//
+// A panic in fn is recovered per element: the element is left out, the
+// *PanicError goes through the error handler and the first one is reported
+// by Err().
+func ParallelDescribeBy[T any](p *Pipeline[T], workers int, fn func(T) float64) Stats {
+	defer p.finalize()
+	items, cancelled := drainSourceCtx(p.source, p.ctx)
+	p.setErr(closeSource(p.source))
+	if cancelled {
+		p.setErr(p.ctx.Err())
+	}
+	n := len(items)
+	if n == 0 {
+		return Stats{}
+	}
+	workers = min(max(workers, 1), n)
+
+	// runParallel gives worker w the contiguous range starting at w×batch.
+	batch := (n + workers - 1) / workers
+	partials := make([]StatsAcc, workers)
+	panics := runParallel(n, workers, func(i int) {
+		x := fn(items[i])
+		partials[i/batch].Add(x)
+	})
+	if len(panics) > 0 {
+		handlePanics(p.hooks, items, panics)
+		p.setErr(panics[0].err)
+	}
+
+	var total StatsAcc
+	for i := range partials {
+		total.Merge(&partials[i])
+	}
+	return total.Result()
+}
@@ -0,0 +1,259 @@
+package gosplice
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func accData(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.Sin(float64(i))*50 + float64(i%7)
+	}
+	return out
+}
+
+func TestAccumulators_MergeMatchesSinglePass(t *testing.T) {
+	data := accData(1001)
+	var mean, meanL, meanR MeanAcc
+	var vr, vrL, vrR VarianceAcc
+	var mm, mmL, mmR MinMaxAcc
+	var c, cL, cR CorrelationAcc
+	for i, x := range data {
+		y := x*2 + math.Cos(float64(i))
+		mean.Add(x)
+		vr.Add(x)
+		mm.Add(x)
+		c.Add(x, y)
+		if i < 400 {
+			meanL.Add(x)
+			vrL.Add(x)
+			mmL.Add(x)
+			cL.Add(x, y)
+		} else {
+			meanR.Add(x)
+			vrR.Add(x)
+			mmR.Add(x)
+			cR.Add(x, y)
+		}
+	}
+	meanL.Merge(&meanR)
+	vrL.Merge(&vrR)
+	mmL.Merge(&mmR)
+	cL.Merge(&cR)
+
+	if !approxEqual(meanL.Result(), mean.Result(), 1e-9) {
+		t.Errorf("mean %v vs %v", meanL.Result(), mean.Result())
+	}
+	if !approxEqual(vrL.Result(), vr.Result(), 1e-9) || vrL.Count != vr.Count {
+		t.Errorf("variance %v vs %v", vrL.Result(), vr.Result())
+	}
+	if mmL != mm {
+		t.Errorf("minmax %+v vs %+v", mmL, mm)
+	}
+	if !approxEqual(cL.Result(), c.Result(), 1e-12) || !approxEqual(cL.Covariance(), c.Covariance(), 1e-9) {
+		t.Errorf("correlation %v vs %v", cL.Result(), c.Result())
+	}
+	if !approxEqual(c.Result(), Correlation(data, slices.Collect(func(yield func(float64) bool) {
+		for i, x := range data {
+			if !yield(x*2 + math.Cos(float64(i))) {
+				return
+			}
+		}
+	})), 1e-12) {
+		t.Error("CorrelationAcc disagrees with Correlation")
+	}
+}
+
+func TestAccumulators_MergeEmpty(t *testing.T) {
+	var a, empty VarianceAcc
+	a.Add(1)
+	a.Add(3)
+	before := a
+	a.Merge(&empty)
+	if a != before {
+		t.Error("merging an empty accumulator changed the result")
+	}
+	empty.Merge(&a)
+	if empty != a {
+		t.Error("merging into an empty accumulator should copy")
+	}
+	var mm MinMaxAcc
+	mm.Add(-5)
+	if lo, hi := mm.Result(); lo != -5 || hi != -5 {
+		t.Errorf("single value minmax = %v, %v", lo, hi)
+	}
+}
+
+func TestHistogramAcc(t *testing.T) {
+	h := NewHistogramAcc(0, 10, 5)
+	for _, x := range []float64{-1, 0, 1.9, 2, 5, 9.99, 10, 11} {
+		h.Add(x)
+	}
+	edges, counts := h.Result()
+	if !slices.Equal(edges, []float64{0, 2, 4, 6, 8, 10}) {
+		t.Errorf("edges %v", edges)
+	}
+	if !slices.Equal(counts, []int{2, 1, 1, 0, 2}) || h.Under != 1 || h.Over != 1 {
+		t.Errorf("counts %v under %d over %d", counts, h.Under, h.Over)
+	}
+
+	other := NewHistogramAcc(0, 10, 5)
+	other.Add(3)
+	if err := h.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if _, counts := h.Result(); counts[1] != 2 {
+		t.Errorf("merged counts %v", counts)
+	}
+
+	before := slices.Clone(h.Counts)
+	if err := h.Merge(NewHistogramAcc(0, 10, 4)); err == nil {
+		t.Error("merging different bins should fail")
+	}
+	if err := h.Merge(NewHistogramAcc(0, 20, 5)); err == nil {
+		t.Error("merging a different range should fail")
+	}
+	if !slices.Equal(h.Counts, before) {
+		t.Errorf("failed merge changed counts %v", h.Counts)
+	}
+}
+
+func TestAccumulators_NaNRule(t *testing.T) {
+	id := func(x float64) float64 { return x }
+	nan := math.NaN()
+	for _, in := range [][]float64{{nan, 3, 1, 2}, {3, 1, nan, 2}, {3, 1, 2, nan}} {
+		var mm MinMaxAcc
+		var mean MeanAcc
+		h := NewHistogramAcc(0, 4, 2)
+		var st StatsAcc
+		for _, x := range in {
+			mm.Add(x)
+			mean.Add(x)
+			h.Add(x)
+			st.Add(x)
+		}
+		if lo, hi := mm.Result(); lo != 1 || hi != 3 || mm.Count != 3 {
+			t.Errorf("%v: MinMaxAcc %v %v over %d values", in, lo, hi, mm.Count)
+		}
+		if !math.IsNaN(mean.Result()) || mean.Count != 4 {
+			t.Errorf("%v: MeanAcc %v over %d values", in, mean.Result(), mean.Count)
+		}
+		if _, counts := h.Result(); !slices.Equal(counts, []int{1, 2}) || h.NaN != 1 {
+			t.Errorf("%v: HistogramAcc %v, NaN %d", in, counts, h.NaN)
+		}
+
+		want := DescribeBy(FromSlice(in), id)
+		for name, got := range map[string]Stats{
+			"StatsAcc":           st.Result(),
+			"ApproxDescribeBy":   ApproxDescribeBy(FromSlice(in), id),
+			"ParallelDescribeBy": ParallelDescribeBy(FromSlice(in), 2, id),
+		} {
+			if got.Count != want.Count || got.Min != want.Min || got.Max != want.Max {
+				t.Errorf("%v: %s %+v, want %+v", in, name, got, want)
+			}
+			for _, x := range []float64{got.Sum, got.Mean, got.StdDev, got.Q1, got.Median, got.Q3} {
+				if !math.IsNaN(x) {
+					t.Errorf("%v: %s %+v, want NaN moments and quartiles", in, name, got)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestStatsAcc_ExactSum(t *testing.T) {
+	var st StatsAcc
+	var sum float64
+	for i := range 10 {
+		x := 0.1 * float64(i+1)
+		st.Add(x)
+		sum += x
+	}
+	// Mean×Count would give 5.5.
+	if got := st.Result().Sum; got != sum {
+		t.Errorf("sum: got %v, want %v", got, sum)
+	}
+}
+
+func TestAccumulators_Serialisation(t *testing.T) {
+	var v VarianceAcc
+	var s StatsAcc
+	h := NewHistogramAcc(0, 100, 10)
+	for _, x := range accData(2000) {
+		v.Add(x)
+		s.Add(x)
+		h.Add(x)
+	}
+
+	js, err := json.Marshal(struct {
+		V VarianceAcc
+		S *StatsAcc
+		H *HistogramAcc
+	}{v, &s, h})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON struct {
+		V VarianceAcc
+		S *StatsAcc
+		H *HistogramAcc
+	}
+	if err := json.Unmarshal(js, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON.V != v || fromJSON.S.Result() != s.Result() || !slices.Equal(fromJSON.H.Counts, h.Counts) {
+		t.Errorf("JSON round trip changed results: %+v", fromJSON.S.Result())
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&s); err != nil {
+		t.Fatal(err)
+	}
+	var fromGob StatsAcc
+	if err := gob.NewDecoder(&buf).Decode(&fromGob); err != nil {
+		t.Fatal(err)
+	}
+	if fromGob.Result() != s.Result() {
+		t.Errorf("gob round trip: %+v vs %+v", fromGob.Result(), s.Result())
+	}
+}
+
+func TestParallelDescribeBy(t *testing.T) {
+	data := accData(500)
+	id := func(x float64) float64 { return x }
+	want := DescribeBy(FromSlice(data), id)
+	for _, workers := range []int{1, 3, 8, 1000} {
+		got := ParallelDescribeBy(FromSlice(data), workers, id)
+		if got.Count != want.Count || got.Min != want.Min || got.Max != want.Max ||
+			!approxEqual(got.Mean, want.Mean, 1e-9) || !approxEqual(got.StdDev, want.StdDev, 1e-9) ||
+			!approxEqual(got.Median, want.Median, 0.05*want.StdDev) { // merged sketches are approximate
+			t.Errorf("workers=%d: got %+v, want %+v", workers, got, want)
+		}
+	}
+	if (ParallelDescribeBy(FromSlice([]float64{}), 4, id) != Stats{}) {
+		t.Error("empty input should give zero Stats")
+	}
+}
+
+func TestParallelDescribeBy_Panic(t *testing.T) {
+	p := FromSlice([]float64{1, 2, -1, 3})
+	got := ParallelDescribeBy(p, 2, func(x float64) float64 {
+		if x < 0 {
+			panic("negative")
+		}
+		return x
+	})
+	if got.Count != 3 || got.Max != 3 {
+		t.Errorf("got %+v", got)
+	}
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
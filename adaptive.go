@@ -0,0 +1,299 @@
+package gosplice
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// AdaptiveRateConfig
+// ---------------------------------------------------------------------------
+
+// AdaptiveRateConfig controls an AdaptiveLimiter. Rates are elements per
+// Interval, as in RateLimitConfig.
+type AdaptiveRateConfig struct {
+	// Rate is the starting rate.
+	Rate float64
+
+	// MinRate is the floor the rate never drops below. Defaults to 1.
+	MinRate float64
+
+	// MaxRate is the ceiling additive increases stop at. Defaults to Rate,
+	// so the limiter backs off under errors and recovers to where it
+	// started.
+	MaxRate float64
+
+	// Interval is the time window for the rates. Defaults to 1 second.
+	Interval time.Duration
+
+	// Burst is the token bucket capacity. Defaults to the current rate
+	// (at least 1), so it shrinks as the limiter backs off.
+	Burst int
+
+	// Increase is added to the rate once per Interval in which elements
+	// succeeded and no error was reported. Defaults to 1.
+	Increase float64
+
+	// Backoff multiplies the rate on an error. Defaults to 0.5.
+	Backoff float64
+
+	// Cooldown is the minimum time between two decreases, so a burst of
+	// errors from requests already in flight counts once. Defaults to
+	// Interval.
+	Cooldown time.Duration
+
+	// LatencyTarget, if set, makes a success slower than the target count
+	// as an error: the limiter backs off before the downstream fails.
+	LatencyTarget time.Duration
+
+	// IsThrottle reports whether an error should slow the limiter down
+	// (e.g. only 429 and 503 responses). Nil means every error does; other
+	// errors are ignored.
+	IsThrottle func(error) bool
+
+	// OnRateChange is called after every change with the old and new rate.
+	// It runs on the goroutine that reported the feedback.
+	OnRateChange func(oldRate, newRate float64)
+
+	// Now returns the current time. Defaults to time.Now; override in tests.
+	Now func() time.Time
+}
+
+func (c AdaptiveRateConfig) withDefaults() AdaptiveRateConfig {
+	if c.MinRate <= 0 {
+		c.MinRate = 1
+	}
+	if c.MaxRate <= 0 {
+		c.MaxRate = c.Rate
+	}
+	c.MaxRate = max(c.MaxRate, c.MinRate)
+	c.Rate = min(max(c.Rate, c.MinRate), c.MaxRate)
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Increase <= 0 {
+		c.Increase = 1
+	}
+	if c.Backoff <= 0 || c.Backoff >= 1 {
+		c.Backoff = 0.5
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = c.Interval
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	return c
+}
+
+// ---------------------------------------------------------------------------
+// AdaptiveLimiter
+// ---------------------------------------------------------------------------
+
+// AdaptiveLimiter is a token bucket whose rate follows AIMD (additive
+// increase, multiplicative decrease), the scheme TCP uses for congestion
+// control: an error cuts the rate by Backoff, and every Interval with
+// successes and no errors adds Increase, within [MinRate, MaxRate].
+//
+// Feed it from a pipeline with RateLimitAdaptive plus AdaptiveErrorHook or
+// AdaptiveErrorHandler, or call Feedback directly. Safe for concurrent use;
+// one limiter can throttle several pipelines.
+type AdaptiveLimiter struct {
+	cfg     AdaptiveRateConfig
+	limiter *RateLimiter
+
+	mu          sync.Mutex
+	rate        float64
+	errSeq      uint64    // errors reported so far
+	lastChange  time.Time // start of the current increase period
+	lastDecr    time.Time
+	erroredHere bool // an error was seen in the current period
+}
+
+// NewAdaptiveLimiter creates a limiter starting at cfg.Rate.
+func NewAdaptiveLimiter(cfg AdaptiveRateConfig) *AdaptiveLimiter {
+	cfg = cfg.withDefaults()
+	l := &AdaptiveLimiter{cfg: cfg, rate: cfg.Rate, lastChange: cfg.Now()}
+	l.limiter = NewRateLimiter(RateLimitConfig{Interval: cfg.Interval, Burst: 1})
+	l.limiter.set(cfg.Rate, l.burst(cfg.Rate))
+	l.limiter.bucket.tokens = l.limiter.bucket.max // start full
+	return l
+}
+
+// Rate returns the current rate in elements per Interval.
+func (l *AdaptiveLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Feedback reports the outcome of one operation. A non-nil err that
+// IsThrottle accepts, or a latency above LatencyTarget, backs the rate
+// off; anything else counts as a success. Pass latency 0 if unknown.
+func (l *AdaptiveLimiter) Feedback(err error, latency time.Duration) {
+	switch {
+	case err != nil:
+		if l.cfg.IsThrottle == nil || l.cfg.IsThrottle(err) {
+			l.decrease()
+		}
+	case l.cfg.LatencyTarget > 0 && latency > l.cfg.LatencyTarget:
+		l.decrease()
+	default:
+		l.success()
+	}
+}
+
+// Wait blocks until the limiter allows one element. It returns ctx.Err()
+// if ctx is cancelled first; a nil ctx waits indefinitely.
+func (l *AdaptiveLimiter) Wait(ctx context.Context) error {
+	return l.limiter.Wait(ctx)
+}
+
+// ReserveN implements Limiter at the current rate, so an AdaptiveLimiter
+// can be a tier of a MultiLimiter.
+func (l *AdaptiveLimiter) ReserveN(n float64) (time.Duration, func()) { return l.limiter.ReserveN(n) }
+
+// WaitN implements Limiter.
+func (l *AdaptiveLimiter) WaitN(ctx context.Context, n float64) error { return l.limiter.WaitN(ctx, n) }
+
+func (l *AdaptiveLimiter) burst(rate float64) float64 {
+	if l.cfg.Burst > 0 {
+		return float64(l.cfg.Burst)
+	}
+	return max(rate, 1)
+}
+
+func (l *AdaptiveLimiter) decrease() {
+	l.mu.Lock()
+	now := l.cfg.Now()
+	l.errSeq++
+	l.erroredHere = true
+	if !l.lastDecr.IsZero() && now.Sub(l.lastDecr) < l.cfg.Cooldown {
+		l.mu.Unlock()
+		return
+	}
+	l.lastDecr = now
+	l.setLocked(max(l.rate*l.cfg.Backoff, l.cfg.MinRate), now)
+}
+
+func (l *AdaptiveLimiter) success() {
+	l.mu.Lock()
+	now := l.cfg.Now()
+	if now.Sub(l.lastChange) < l.cfg.Interval {
+		l.mu.Unlock()
+		return
+	}
+	if l.erroredHere {
+		// The period ended with an error in it: start a fresh one.
+		l.lastChange, l.erroredHere = now, false
+		l.mu.Unlock()
+		return
+	}
+	l.setLocked(min(l.rate+l.cfg.Increase, l.cfg.MaxRate), now)
+}
+
+// setLocked applies a new rate, starts a new increase period and fires
+// OnRateChange. Called with mu held; releases it.
+func (l *AdaptiveLimiter) setLocked(rate float64, now time.Time) {
+	old := l.rate
+	l.rate = rate
+	l.lastChange, l.erroredHere = now, false
+	if rate != old {
+		l.limiter.mu.Lock()
+		l.limiter.set(rate, l.burst(rate))
+		l.limiter.mu.Unlock()
+	}
+	l.mu.Unlock()
+	if rate != old && l.cfg.OnRateChange != nil {
+		l.cfg.OnRateChange(old, rate)
+	}
+}
+
+// errCount returns the number of errors reported so far.
+func (l *AdaptiveLimiter) errCount() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.errSeq
+}
+
+// ---------------------------------------------------------------------------
+// Pipeline integration
+// ---------------------------------------------------------------------------
+
+// RateLimitAdaptive throttles the pipeline with an AdaptiveLimiter. Like
+// RateLimit it is lazy and waits on the pipeline's context.
+//
+// Downstream outcomes reach the limiter in two ways. Errors are reported by
+// registering AdaptiveErrorHook (or wrapping the error handler with
+// AdaptiveErrorHandler) on the pipeline that PipeMapErr consumes. Successes
+// are inferred: when the next element is pulled and no error was reported
+// since the previous one, that element succeeded.
+//
+//	lim := gs.NewAdaptiveLimiter(gs.AdaptiveRateConfig{Rate: 100, MinRate: 5})
+//	src := gs.FromSlice(urls).
+//	    RateLimitAdaptive(lim).
+//	    WithErrorHook(gs.AdaptiveErrorHook[string](lim))
+//	bodies := gs.PipeMapErr(src, fetch).Collect()
+func (p *Pipeline[T]) RateLimitAdaptive(l *AdaptiveLimiter) *Pipeline[T] {
+	return &Pipeline[T]{
+		source:  &adaptiveLimitSource[T]{inner: p.source, limiter: l, ctx: p.ctx},
+		hooks:   p.hooks,
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+// AdaptiveErrorHook returns an ErrorHook that reports every error to l.
+func AdaptiveErrorHook[T any](l *AdaptiveLimiter) ErrorHook[T] {
+	return func(err error, _ T) { l.Feedback(err, 0) }
+}
+
+// AdaptiveErrorHandler returns an ErrorHandler that reports every error to
+// l and then defers to next (Skip if nil). Use it instead of
+// AdaptiveErrorHook when the pipeline has an ErrorHandler, since error
+// hooks do not fire then.
+func AdaptiveErrorHandler[T any](l *AdaptiveLimiter, next ErrorHandler[T]) ErrorHandler[T] {
+	return func(err error, elem T, attempt int) ErrorAction {
+		l.Feedback(err, 0)
+		if next == nil {
+			return Skip
+		}
+		return next(err, elem, attempt)
+	}
+}
+
+type adaptiveLimitSource[T any] struct {
+	inner   Source[T]
+	limiter *AdaptiveLimiter
+	ctx     context.Context
+	pulled  bool
+	errSeq  uint64
+}
+
+func (s *adaptiveLimitSource[T]) Next() (T, bool) {
+	if s.pulled && s.limiter.errCount() == s.errSeq {
+		s.limiter.success()
+	}
+	if s.limiter.Wait(s.ctx) != nil {
+		var zero T
+		return zero, false
+	}
+	v, ok := s.inner.Next()
+	s.pulled = ok
+	s.errSeq = s.limiter.errCount()
+	return v, ok
+}
+
+func (s *adaptiveLimitSource[T]) SizeHint() int {
+	if sizer, ok := s.inner.(Sizer); ok {
+		return sizer.SizeHint()
+	}
+	return -1
+}
+
+func (s *adaptiveLimitSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *adaptiveLimitSource[T]) Close() error { return closeSource(s.inner) }
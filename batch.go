@@ -2,59 +2,170 @@ package gosplice
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
 type BatchConfig struct {
+	// Size is the number of elements per batch. On its own, a Size of zero
+	// or less makes every batch full before its first element: PipeBatch
+	// emits nothing, or one batch per element with MaxWait.
 	Size int
 	// MaxWait emits a partial batch if no new element arrives within this duration.
 	// Zero means wait indefinitely for a full batch. Only useful with streaming sources.
 	MaxWait time.Duration
+
+	// MinSize holds back MaxWait flushes of batches smaller than this, so a
+	// slow trickle does not turn into many tiny writes. A batch is still
+	// emitted when it fills up, is split, or the input ends.
+	MinSize int
+
+	// Shed selects what happens to a full batch when the consumer has not
+	// taken the previous ones (a few batches are buffered). ShedBlock, the
+	// default, stops reading until it does; see ShedPolicy for the others.
+	// Only used with MaxWait, where the source is read ahead. The final
+	// batch is never shed.
+	Shed ShedPolicy
+}
+
+// BatchByConfig extends BatchConfig with per-element limits for
+// PipeBatchBy. Size may be zero to batch by weight or key alone.
+type BatchByConfig[T any] struct {
+	BatchConfig
+
+	// MaxBytes caps the total SizeOf of a batch, e.g. the payload limit of
+	// a bulk endpoint. An element that would push the batch over starts the
+	// next one; an element larger than MaxBytes is emitted on its own.
+	MaxBytes int
+
+	// SizeOf returns the weight of an element, usually its encoded size in
+	// bytes. Required for MaxBytes.
+	SizeOf func(T) int
+
+	// SplitBy ends the current batch before next when it returns true, so
+	// a batch never mixes e.g. two tables or two tenants. prev is the last
+	// element of the batch.
+	SplitBy func(prev, next T) bool
 }
 
 func PipeBatch[T any](p *Pipeline[T], cfg BatchConfig) *Pipeline[[]T] {
-	src := p.source
-	hooks := p.hooks
-	done := false
+	return PipeBatchBy(p, BatchByConfig[T]{BatchConfig: cfg})
+}
 
+// PipeBatchBy groups elements into batches bounded by count, total weight
+// and key, whichever comes first. OnBatch hooks see every batch, and with
+// MaxWait the context behaves as in PipeBatch.
+//
+//	bulks := gs.PipeBatchBy(rows, gs.BatchByConfig[Row]{
+//	    BatchConfig: gs.BatchConfig{Size: 500, MaxWait: time.Second, MinSize: 50},
+//	    MaxBytes:    1 << 20,
+//	    SizeOf:      func(r Row) int { return len(r.JSON) },
+//	    SplitBy:     func(a, b Row) bool { return a.Table != b.Table },
+//	})
+func PipeBatchBy[T any](p *Pipeline[T], cfg BatchByConfig[T]) *Pipeline[[]T] {
 	if cfg.MaxWait > 0 {
-		return pipeBatchWithTimeout(src, hooks, cfg, p.ctx, p.cancel, p.ctxNoop)
+		return pipeBatchWithTimeout(p.source, p.hooks, cfg, p.ctx, p.cancel, p.stop, p.ctxNoop)
 	}
 
 	return &Pipeline[[]T]{
-		source: &funcSource[[]T]{fn: func() ([]T, bool) {
-			if done {
-				return nil, false
-			}
-			batch := make([]T, 0, cfg.Size)
-			for len(batch) < cfg.Size {
-				v, ok := src.Next()
-				if !ok {
-					done = true
-					break
-				}
-				hooks.fireElement(v)
-				batch = append(batch, v)
-			}
-			if len(batch) == 0 {
-				return nil, false
-			}
-			hooks.fireBatch(batch)
-			return batch, true
-		}},
-		hooks:   newHooks[[]T](),
+		source:  &batchSource[T]{inner: p.source, b: newBatcher(cfg), hooks: p.hooks},
+		hooks:   inheritHooks[[]T](p.hooks),
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
 
-func pipeBatchWithTimeout[T any](src Source[T], hooks *Hooks[T], cfg BatchConfig, ctx context.Context, cancel context.CancelFunc, ctxNoop bool) *Pipeline[[]T] {
+// batcher accumulates elements under the limits of a BatchByConfig.
+type batcher[T any] struct {
+	cfg   BatchByConfig[T]
+	batch []T
+	bytes int
+}
+
+func newBatcher[T any](cfg BatchByConfig[T]) *batcher[T] {
+	if cfg.SizeOf == nil {
+		cfg.MaxBytes = 0
+	}
+	return &batcher[T]{cfg: cfg, batch: make([]T, 0, max(cfg.Size, 0))}
+}
+
+// add appends v. If v cannot join the current batch, that batch is
+// returned first and v starts the next one.
+func (b *batcher[T]) add(v T) (out []T) {
+	var w int
+	if b.cfg.MaxBytes > 0 {
+		w = b.cfg.SizeOf(v)
+	}
+	if n := len(b.batch); n > 0 {
+		if (b.cfg.MaxBytes > 0 && b.bytes+w > b.cfg.MaxBytes) ||
+			(b.cfg.SplitBy != nil && b.cfg.SplitBy(b.batch[n-1], v)) {
+			out = b.take()
+		}
+	}
+	b.batch = append(b.batch, v)
+	b.bytes += w
+	return out
+}
+
+// full reports whether the batch reached Size or MaxBytes. Size bounds
+// the batch when it is positive or when there is no other limit.
+func (b *batcher[T]) full() bool {
+	sized := b.cfg.Size > 0 || (b.cfg.MaxBytes <= 0 && b.cfg.SplitBy == nil)
+	return (sized && len(b.batch) >= b.cfg.Size) ||
+		(b.cfg.MaxBytes > 0 && b.bytes >= b.cfg.MaxBytes)
+}
+
+// take returns the current batch and starts an empty one.
+func (b *batcher[T]) take() []T {
+	out := b.batch
+	b.batch, b.bytes = make([]T, 0, max(b.cfg.Size, 0)), 0
+	return out
+}
+
+type batchSource[T any] struct {
+	inner Source[T]
+	b     *batcher[T]
+	hooks *Hooks[T]
+	done  bool
+}
+
+func (s *batchSource[T]) Next() ([]T, bool) {
+	for !s.done && !s.b.full() {
+		v, ok := s.inner.Next()
+		if !ok {
+			s.done = true
+			break
+		}
+		s.hooks.fireElement(v)
+		if out := s.b.add(v); out != nil {
+			s.hooks.fireBatch(out)
+			return out, true
+		}
+	}
+	if len(s.b.batch) == 0 {
+		return nil, false
+	}
+	batch := s.b.take()
+	s.hooks.fireBatch(batch)
+	return batch, true
+}
+
+func (s *batchSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *batchSource[T]) Close() error { return closeSource(s.inner) }
+
+func pipeBatchWithTimeout[T any](src Source[T], hooks *Hooks[T], cfg BatchByConfig[T], ctx context.Context, cancel context.CancelFunc, stop func(), ctxNoop bool) *Pipeline[[]T] {
 	outCh := make(chan []T, 4)
+	errs := &errSlot{}
+	// closed is signalled by Close (or finalize) when the consumer is gone.
+	// Unlike ctx cancellation it does not flush: nobody is left to receive.
+	closed := make(chan struct{})
 
 	go func() {
 		defer close(outCh)
-		batch := make([]T, 0, cfg.Size)
+		b := newBatcher(cfg)
 		timer := time.NewTimer(cfg.MaxWait)
 		defer timer.Stop()
 
@@ -67,26 +178,62 @@ func pipeBatchWithTimeout[T any](src Source[T], hooks *Hooks[T], cfg BatchConfig
 		go func() {
 			defer close(itemCh)
 			for {
-				v, ok := src.Next()
+				v, ok, perr := nextRecover(src)
+				if perr != nil {
+					errs.set(perr)
+					return
+				}
 				if !ok {
+					errs.set(innerErr(src))
 					return
 				}
 				select {
 				case itemCh <- v:
 				case <-loopCtx.Done():
 					return
+				case <-closed:
+					return
 				}
 			}
 		}()
 
-		flush := func() {
-			if len(batch) > 0 {
-				out := make([]T, len(batch))
-				copy(out, batch)
-				hooks.fireBatch(out)
-				outCh <- out
-				batch = batch[:0]
+		// send delivers out, shedding under cfg.Shed when outCh is full.
+		// Returns false when the stage stops.
+		send := func(out []T, shed bool) bool {
+			if !shed || cfg.Shed == ShedBlock {
+				select {
+				case outCh <- out:
+					return true
+				case <-closed:
+					return false
+				}
 			}
+			for {
+				select {
+				case outCh <- out:
+					return true
+				default:
+				}
+				if cfg.Shed == ShedDropOldest {
+					select {
+					case old := <-outCh:
+						for _, v := range old {
+							hooks.fireShed(v)
+						}
+					default:
+					}
+					continue
+				}
+				for _, v := range out {
+					if shedOne(hooks, cfg.Shed, v, errs) {
+						return false
+					}
+				}
+				return true
+			}
+		}
+
+		resetTimer := func() {
 			if !timer.Stop() {
 				select {
 				case <-timer.C:
@@ -96,30 +243,98 @@ func pipeBatchWithTimeout[T any](src Source[T], hooks *Hooks[T], cfg BatchConfig
 			timer.Reset(cfg.MaxWait)
 		}
 
+		emit := func(out []T, shed bool) bool {
+			hooks.fireBatch(out)
+			if !send(out, shed) {
+				return false
+			}
+			resetTimer()
+			return true
+		}
+
+		flush := func(shed bool) bool {
+			if len(b.batch) > 0 {
+				return emit(b.take(), shed)
+			}
+			resetTimer()
+			return true
+		}
+
 		for {
 			select {
+			case <-closed:
+				return
 			case <-loopCtx.Done():
-				flush()
+				flush(false)
 				return
 			case v, ok := <-itemCh:
 				if !ok {
-					flush()
+					flush(false)
 					return
 				}
 				hooks.fireElement(v)
-				batch = append(batch, v)
-				if len(batch) >= cfg.Size {
-					flush()
+				// SizeOf and SplitBy run here, off the consumer's goroutine:
+				// a panic in them ends the stage like one in the source.
+				out, perr := callRecover(b.add, v)
+				if perr != nil {
+					errs.set(perr)
+					flush(false)
+					return
+				}
+				if out != nil && !emit(out, true) {
+					return
+				}
+				if b.full() && !flush(true) {
+					return
 				}
 			case <-timer.C:
-				flush()
+				if n := len(b.batch); n > 0 && n < cfg.MinSize {
+					timer.Reset(cfg.MaxWait)
+					continue
+				}
+				if !flush(true) {
+					return
+				}
 			}
 		}
 	}()
 
-	p := FromChannel(outCh)
+	bs := &batchChanSource[T]{chanSource: chanSource[[]T]{ch: outCh}, errs: errs, closed: closed, inner: src}
+	p := newPipeline[[]T](bs)
+	p.hooks.RecoverPanics = hooks.RecoverPanics
 	p.ctx = ctx
 	p.cancel = cancel
+	p.stop = func() {
+		bs.stop()
+		if stop != nil {
+			stop()
+		}
+	}
 	p.ctxNoop = ctxNoop
 	return p
 }
+
+// batchChanSource yields batches from the timeout goroutine and reports a
+// panic recovered while reading the upstream source or sizing elements, or
+// else the upstream source's error. The reader records that error once the
+// source is exhausted, so Err never reads the source concurrently with it.
+type batchChanSource[T any] struct {
+	chanSource[[]T]
+	errs   *errSlot
+	closed chan struct{}
+	once   sync.Once
+	inner  any
+}
+
+func (s *batchChanSource[T]) Err() error { return s.errs.get() }
+
+func (s *batchChanSource[T]) stop() {
+	s.once.Do(func() { close(s.closed) })
+}
+
+// Close stops the batching goroutine and closes the upstream source,
+// unblocking the reader if it is parked in Next on an owned reader.
+func (s *batchChanSource[T]) Close() error {
+	s.stop()
+	return closeSource(s.inner)
+}
@@ -2,6 +2,8 @@ package gosplice
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -56,6 +58,16 @@ func TestBatchTimeout_WithCtx_Cancel(t *testing.T) {
 	}
 }
 
+func TestBatchTimeout_WithContextAfter(t *testing.T) {
+	// WithContext on the batched pipeline must not stop the batching goroutine.
+	got := PipeBatch(FromSlice(makeRange(10)), BatchConfig{Size: 3, MaxWait: time.Second}).
+		WithContext(context.Background()).Collect()
+	want := [][]int{{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, {9}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 func TestBatch_WithElementAndBatchHooks(t *testing.T) {
 	var elemCount atomic.Int64
 	var batchCount atomic.Int64
@@ -75,3 +87,130 @@ func TestBatch_WithElementAndBatchHooks(t *testing.T) {
 		t.Fatalf("expected 3 batch hooks, got %d", batchCount.Load())
 	}
 }
+
+func TestBatch_NonPositiveSize(t *testing.T) {
+	if got := PipeBatch(FromSlice([]int{1, 2, 3}), BatchConfig{}).Collect(); len(got) != 0 {
+		t.Errorf("Size 0: expected no batches, got %v", got)
+	}
+	got := PipeBatch(FromSlice([]int{1, 2, 3}), BatchConfig{Size: -1, MaxWait: time.Second}).Collect()
+	if want := [][]int{{1}, {2}, {3}}; !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Size -1 with MaxWait: got %v, want %v", got, want)
+	}
+}
+
+func TestPipeBatchBy_MaxBytes(t *testing.T) {
+	words := []string{"aa", "bbb", "c", "dddd", "eeeeeeeeee", "f", "gg"}
+	var batchCount atomic.Int64
+	batches := PipeBatchBy(
+		FromSlice(words).WithBatchHook(CountBatches[string](&batchCount)),
+		BatchByConfig[string]{MaxBytes: 6, SizeOf: func(s string) int { return len(s) }},
+	).Collect()
+	// "eeeeeeeeee" is over the limit on its own and goes alone.
+	want := [][]string{{"aa", "bbb", "c"}, {"dddd"}, {"eeeeeeeeee"}, {"f", "gg"}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Fatalf("got %v, want %v", batches, want)
+	}
+	if batchCount.Load() != 4 {
+		t.Errorf("expected 4 batch hooks, got %d", batchCount.Load())
+	}
+}
+
+func TestPipeBatchBy_SizeAndBytes(t *testing.T) {
+	in := []int{1, 1, 1, 5, 1, 1, 1, 1}
+	batches := PipeBatchBy(FromSlice(in), BatchByConfig[int]{
+		BatchConfig: BatchConfig{Size: 3},
+		MaxBytes:    6,
+		SizeOf:      func(v int) int { return v },
+	}).Collect()
+	want := [][]int{{1, 1, 1}, {5, 1}, {1, 1, 1}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Fatalf("got %v, want %v", batches, want)
+	}
+}
+
+func TestPipeBatchBy_SplitBy(t *testing.T) {
+	type row struct {
+		table string
+		id    int
+	}
+	in := []row{{"a", 1}, {"a", 2}, {"a", 3}, {"b", 4}, {"a", 5}, {"a", 6}}
+	batches := PipeBatchBy(FromSlice(in), BatchByConfig[row]{
+		BatchConfig: BatchConfig{Size: 2},
+		SplitBy:     func(prev, next row) bool { return prev.table != next.table },
+	}).Collect()
+	var got [][]int
+	for _, b := range batches {
+		var ids []int
+		for _, r := range b {
+			ids = append(ids, r.id)
+		}
+		got = append(got, ids)
+	}
+	want := [][]int{{1, 2}, {3}, {4}, {5, 6}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPipeBatchBy_TimeoutWithBytes(t *testing.T) {
+	ch := make(chan string)
+	go func() {
+		for _, s := range []string{"aaaa", "bbbb", "cc"} {
+			ch <- s
+		}
+		close(ch)
+	}()
+	batches := PipeBatchBy(FromChannel(ch), BatchByConfig[string]{
+		BatchConfig: BatchConfig{MaxWait: time.Hour},
+		MaxBytes:    8,
+		SizeOf:      func(s string) int { return len(s) },
+	}).Collect()
+	want := [][]string{{"aaaa", "bbbb"}, {"cc"}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Fatalf("got %v, want %v", batches, want)
+	}
+}
+
+func TestPipeBatchBy_MinSizeHoldsTimeoutFlush(t *testing.T) {
+	ch := make(chan int)
+	go func() {
+		// One element per 30ms; MaxWait 10ms would flush each alone.
+		for i := 1; i <= 4; i++ {
+			ch <- i
+			time.Sleep(30 * time.Millisecond)
+		}
+		close(ch)
+	}()
+	batches := PipeBatch(FromChannel(ch), BatchConfig{
+		Size: 10, MaxWait: 10 * time.Millisecond, MinSize: 2,
+	}).Collect()
+	for i, b := range batches {
+		if len(b) < 2 && i != len(batches)-1 {
+			t.Fatalf("batch %d below MinSize: %v", i, batches)
+		}
+	}
+	if len(batches) < 2 {
+		t.Errorf("expected timeout flushes, got %v", batches)
+	}
+}
+
+func TestPipeBatchBy_TimeoutSplitByPanic(t *testing.T) {
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		ch <- 2
+		close(ch)
+	}()
+	p := PipeBatchBy(FromChannel(ch), BatchByConfig[int]{
+		BatchConfig: BatchConfig{Size: 10, MaxWait: time.Hour},
+		SplitBy:     func(prev, next int) bool { panic("boom") },
+	})
+	batches := p.Collect()
+	if len(batches) != 1 || !slices.Equal(batches[0], []int{1}) {
+		t.Errorf("got %v", batches)
+	}
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) {
+		t.Errorf("Err() = %v, want *PanicError", p.Err())
+	}
+}
@@ -0,0 +1,218 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchSinkConfig controls ForEachBatch.
+type BatchSinkConfig[T any] struct {
+	// Concurrency is the number of batches in flight at once. Defaults to
+	// 1. With more, batches complete out of order.
+	Concurrency int
+
+	// MaxAttempts is the number of calls an item gets, including the
+	// first. Defaults to 3.
+	MaxAttempts int
+
+	// Backoff is the wait before the first retry. It doubles for every
+	// further retry, up to MaxBackoff. Defaults to 100ms.
+	Backoff time.Duration
+
+	// MaxBackoff caps the wait between retries. Defaults to 10s.
+	MaxBackoff time.Duration
+
+	// IsRetryable reports whether a failure is worth another attempt, e.g.
+	// a timeout but not a validation error. Nil retries every error.
+	IsRetryable func(error) bool
+
+	// OnDeadLetter receives every item that failed for good, with its last
+	// error. Calls are serialised, even with Concurrency > 1.
+	OnDeadLetter ErrorHook[T]
+}
+
+func (c BatchSinkConfig[T]) withDefaults() BatchSinkConfig[T] {
+	c.Concurrency = max(c.Concurrency, 1)
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = 100 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 10 * time.Second
+	}
+	return c
+}
+
+// ForEachBatch hands every batch to fn, typically a bulk API call, and
+// retries what failed. fn reports per-item outcomes: a nil []error means
+// every item succeeded, otherwise it must hold one entry per item, nil for
+// success. A non-nil error fails the whole batch (e.g. the request never
+// reached the server). Only failed items are retried, as a smaller batch,
+// after a doubling backoff; items still failing after MaxAttempts, or with
+// an error IsRetryable rejects, go to OnDeadLetter.
+//
+// fn receives the pipeline's context (Background if none); backoff waits
+// end when it is done, and the items still pending are dead-lettered.
+// Every batch read from p is either delivered or dead-lettered.
+//
+// The returned error is the pipeline's (e.g. a context error), joined,
+// when OnDeadLetter is nil, with an error counting the items lost.
+//
+//	err := gs.ForEachBatch(gs.PipeBatch(docs, gs.BatchConfig{Size: 500}),
+//	    gs.BatchSinkConfig[Doc]{
+//	        Concurrency:  4,
+//	        OnDeadLetter: func(err error, d Doc) { dlq.Send(d, err) },
+//	    },
+//	    func(ctx context.Context, batch []Doc) ([]error, error) {
+//	        return es.Bulk(ctx, batch)
+//	    })
+func ForEachBatch[T any](p *Pipeline[[]T], cfg BatchSinkConfig[T], fn func(context.Context, []T) ([]error, error)) error {
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &batchSink[T]{cfg: cfg.withDefaults(), fn: fn, ctx: ctx}
+
+	if s.cfg.Concurrency == 1 {
+		drain(p, s.run)
+	} else {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, s.cfg.Concurrency)
+		drain(p, func(batch []T) {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				s.run(batch)
+			}()
+		})
+		wg.Wait()
+	}
+	// Finalize only now: it cancels the pipeline's context, which batches
+	// still in flight are using.
+	p.finalize()
+
+	var lost error
+	if s.lost > 0 {
+		lost = fmt.Errorf("gosplice: ForEachBatch: %d items failed: %w", s.lost, s.firstErr)
+	}
+	return errors.Join(p.Err(), lost)
+}
+
+type batchSink[T any] struct {
+	cfg BatchSinkConfig[T]
+	fn  func(context.Context, []T) ([]error, error)
+	ctx context.Context
+
+	mu       sync.Mutex
+	lost     int
+	firstErr error
+}
+
+// run delivers one batch, retrying its failed items.
+func (s *batchSink[T]) run(batch []T) {
+	pending := batch
+	for attempt := 1; ; attempt++ {
+		itemErrs, perr, err := s.call(pending)
+		if perr != nil {
+			// A bug in fn, not a transient failure: do not retry.
+			s.deadLetter(pending, nil, perr)
+			return
+		}
+		if err == nil && itemErrs != nil && len(itemErrs) != len(pending) {
+			s.deadLetter(pending, nil, fmt.Errorf("gosplice: ForEachBatch: fn returned %d errors for %d items", len(itemErrs), len(pending)))
+			return
+		}
+
+		var retry []T
+		var retryErrs []error
+		for i, v := range pending {
+			e := err
+			if e == nil && itemErrs != nil {
+				e = itemErrs[i]
+			}
+			if e == nil {
+				continue
+			}
+			if attempt < s.cfg.MaxAttempts && s.retryable(e) {
+				retry = append(retry, v)
+				retryErrs = append(retryErrs, e)
+				continue
+			}
+			s.deadLetter([]T{v}, nil, e)
+		}
+		if len(retry) == 0 {
+			return
+		}
+		if !s.sleep(s.backoff(attempt)) {
+			s.deadLetter(retry, retryErrs, nil)
+			return
+		}
+		pending = retry
+	}
+}
+
+// call runs fn, recovering a panic since it may run on a worker goroutine.
+func (s *batchSink[T]) call(batch []T) (itemErrs []error, perr *PanicError, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			perr = newPanicError(r)
+		}
+	}()
+	itemErrs, err = s.fn(s.ctx, batch)
+	return itemErrs, nil, err
+}
+
+func (s *batchSink[T]) retryable(err error) bool {
+	return s.cfg.IsRetryable == nil || s.cfg.IsRetryable(err)
+}
+
+// backoff returns the wait after the given attempt.
+func (s *batchSink[T]) backoff(attempt int) time.Duration {
+	d := s.cfg.Backoff
+	for i := 1; i < attempt && d < s.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, s.cfg.MaxBackoff)
+}
+
+// sleep waits d, returning false if the context is done first.
+func (s *batchSink[T]) sleep(d time.Duration) bool {
+	if s.ctx.Err() != nil {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// deadLetter reports items as lost, each with errs[i], or with err when
+// errs is nil.
+func (s *batchSink[T]) deadLetter(items []T, errs []error, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, v := range items {
+		e := err
+		if errs != nil {
+			e = errs[i]
+		}
+		if s.cfg.OnDeadLetter != nil {
+			s.cfg.OnDeadLetter(e, v)
+			continue
+		}
+		if s.lost == 0 {
+			s.firstErr = e
+		}
+		s.lost++
+	}
+}
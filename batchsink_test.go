@@ -0,0 +1,188 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBulk = errors.New("bulk: item rejected")
+
+// flakyBulk fails item v on its first fails[v] attempts.
+type flakyBulk struct {
+	mu    sync.Mutex
+	fails map[int]int
+	calls [][]int
+}
+
+func (b *flakyBulk) call(_ context.Context, batch []int) ([]error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, slices.Clone(batch))
+	errs := make([]error, len(batch))
+	for i, v := range batch {
+		if b.fails[v] > 0 {
+			b.fails[v]--
+			errs[i] = errBulk
+		}
+	}
+	return errs, nil
+}
+
+func TestForEachBatch_RetriesOnlyFailedItems(t *testing.T) {
+	bulk := &flakyBulk{fails: map[int]int{2: 1, 5: 2}}
+	err := ForEachBatch(PipeBatch(FromSlice([]int{1, 2, 3, 4, 5, 6}), BatchConfig{Size: 3}),
+		BatchSinkConfig[int]{Backoff: time.Millisecond}, bulk.call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{1, 2, 3}, {2}, {4, 5, 6}, {5}, {5}}
+	if !slices.EqualFunc(bulk.calls, want, slices.Equal) {
+		t.Errorf("calls %v, want %v", bulk.calls, want)
+	}
+}
+
+func TestForEachBatch_DeadLetter(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	bulk := func(_ context.Context, batch []int) ([]error, error) {
+		errs := make([]error, len(batch))
+		for i, v := range batch {
+			switch v {
+			case 2:
+				errs[i] = errBulk // always fails, retryable
+			case 3:
+				errs[i] = errInvalid // never retried
+			}
+		}
+		return errs, nil
+	}
+	var mu sync.Mutex
+	dead := map[int]error{}
+	err := ForEachBatch(PipeBatch(FromSlice([]int{1, 2, 3, 4}), BatchConfig{Size: 4}),
+		BatchSinkConfig[int]{
+			MaxAttempts:  2,
+			Backoff:      time.Millisecond,
+			IsRetryable:  func(err error) bool { return !errors.Is(err, errInvalid) },
+			OnDeadLetter: func(err error, v int) { mu.Lock(); dead[v] = err; mu.Unlock() },
+		}, bulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 2 || dead[2] != errBulk || dead[3] != errInvalid {
+		t.Errorf("dead letters %v", dead)
+	}
+}
+
+func TestForEachBatch_BatchErrorRetriesWholeBatch(t *testing.T) {
+	var calls atomic.Int32
+	bulk := func(_ context.Context, batch []int) ([]error, error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("connection reset")
+		}
+		if len(batch) != 3 {
+			t.Errorf("retry got %v", batch)
+		}
+		return nil, nil
+	}
+	err := ForEachBatch(PipeBatch(FromSlice([]int{1, 2, 3}), BatchConfig{Size: 3}),
+		BatchSinkConfig[int]{Backoff: time.Millisecond}, bulk)
+	if err != nil || calls.Load() != 2 {
+		t.Errorf("err %v, calls %d", err, calls.Load())
+	}
+}
+
+func TestForEachBatch_ErrorWithoutDeadLetterHook(t *testing.T) {
+	bulk := func(_ context.Context, batch []int) ([]error, error) {
+		return nil, errBulk
+	}
+	err := ForEachBatch(PipeBatch(FromSlice([]int{1, 2, 3, 4, 5}), BatchConfig{Size: 2}),
+		BatchSinkConfig[int]{MaxAttempts: 1}, bulk)
+	if !errors.Is(err, errBulk) || err.Error() != "gosplice: ForEachBatch: 5 items failed: bulk: item rejected" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestForEachBatch_BadResultsAndPanics(t *testing.T) {
+	bulk := func(_ context.Context, batch []int) ([]error, error) {
+		if batch[0] == 1 {
+			return []error{nil}, nil // wrong length
+		}
+		panic("bulk client bug")
+	}
+	var dead []error
+	err := ForEachBatch(PipeBatch(FromSlice([]int{1, 2, 3, 4}), BatchConfig{Size: 2}),
+		BatchSinkConfig[int]{OnDeadLetter: func(err error, _ int) { dead = append(dead, err) }}, bulk)
+	if err != nil || len(dead) != 4 {
+		t.Fatalf("err %v, dead %v", err, dead)
+	}
+	var pe *PanicError
+	if errors.As(dead[0], &pe) || !errors.As(dead[3], &pe) {
+		t.Errorf("dead letters %v", dead)
+	}
+}
+
+func TestForEachBatch_Concurrency(t *testing.T) {
+	var cur, peak atomic.Int32
+	var total atomic.Int32
+	bulk := func(_ context.Context, batch []int) ([]error, error) {
+		n := cur.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		total.Add(int32(len(batch)))
+		cur.Add(-1)
+		return nil, nil
+	}
+	err := ForEachBatch(PipeBatch(FromSlice(makeRange(100)), BatchConfig{Size: 5}),
+		BatchSinkConfig[int]{Concurrency: 3}, bulk)
+	if err != nil || total.Load() != 100 {
+		t.Fatalf("err %v, total %d", err, total.Load())
+	}
+	if p := peak.Load(); p < 2 || p > 3 {
+		t.Errorf("peak in flight %d", p)
+	}
+}
+
+func TestForEachBatch_CtxCancelDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bulk := func(ctx context.Context, batch []int) ([]error, error) {
+		cancel()
+		return nil, errBulk
+	}
+	var dead atomic.Int32
+	start := time.Now()
+	err := ForEachBatch(PipeBatch(FromSlice([]int{1, 2, 3}).WithContext(ctx), BatchConfig{Size: 3}),
+		BatchSinkConfig[int]{
+			Backoff:      time.Hour,
+			OnDeadLetter: func(err error, _ int) { dead.Add(1) },
+		}, bulk)
+	if time.Since(start) > time.Second {
+		t.Fatal("backoff ignored context")
+	}
+	if dead.Load() != 3 {
+		t.Errorf("dead letters %d", dead.Load())
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBatchSink_Backoff(t *testing.T) {
+	s := &batchSink[int]{cfg: BatchSinkConfig[int]{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}.withDefaults()}
+	var got []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		got = append(got, s.backoff(attempt))
+	}
+	want := []time.Duration{100 * ms, 200 * ms, 400 * ms, 800 * ms, time.Second, time.Second}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v", got)
+	}
+}
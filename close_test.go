@@ -0,0 +1,181 @@
+package gosplice
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type trackingCloser struct {
+	io.Reader
+	closed atomic.Int32
+	err    error
+}
+
+func (c *trackingCloser) Close() error {
+	c.closed.Add(1)
+	return c.err
+}
+
+type closableSource struct {
+	n      int
+	closed atomic.Bool
+}
+
+func (s *closableSource) Next() (int, bool) {
+	if s.closed.Load() {
+		return 0, false
+	}
+	s.n++
+	return s.n, true
+}
+
+func (s *closableSource) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestCloseReleasesOwnedReader(t *testing.T) {
+	rc := &trackingCloser{Reader: strings.NewReader("a\nb\nc\n")}
+	p := FromReadCloser(rc).Filter(func(s string) bool { return s != "" })
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if rc.closed.Load() != 1 {
+		t.Errorf("expected reader closed once, got %d", rc.closed.Load())
+	}
+}
+
+func TestCloseReturnsCloserError(t *testing.T) {
+	boom := errors.New("close failed")
+	rc := &trackingCloser{Reader: strings.NewReader("a\n"), err: boom}
+	if err := PipeMap(FromReadCloser(rc), strings.ToUpper).Close(); err != boom {
+		t.Errorf("expected close error, got %v", err)
+	}
+}
+
+func TestCloseFiresCompletionOnce(t *testing.T) {
+	var completions atomic.Int32
+	p := FromSlice([]int{1, 2, 3}).WithCompletionHook(func() { completions.Add(1) })
+	p.Collect()
+	p.Close()
+	if completions.Load() != 1 {
+		t.Errorf("expected 1 completion, got %d", completions.Load())
+	}
+}
+
+func TestFromReaderNotClosed(t *testing.T) {
+	rc := &trackingCloser{Reader: strings.NewReader("a\nb\n")}
+	FromReader(rc).Take(1).Collect()
+	if rc.closed.Load() != 0 {
+		t.Error("FromReader must not close a reader it does not own")
+	}
+}
+
+func TestTakeClosesUpstream(t *testing.T) {
+	src := &closableSource{}
+	got := PipeMap(newPipeline[int](src), func(n int) int { return n * 2 }).Take(3).Collect()
+	assertSliceEqual(t, []int{2, 4, 6}, got)
+	if !src.closed.Load() {
+		t.Error("expected Take to close upstream after limit")
+	}
+}
+
+func TestFirstClosesUpstream(t *testing.T) {
+	rc := &trackingCloser{Reader: strings.NewReader("a\nb\n")}
+	v, ok := FromReadCloser(rc).First()
+	if !ok || v != "a" {
+		t.Fatalf("expected (a, true), got (%q, %v)", v, ok)
+	}
+	if rc.closed.Load() != 1 {
+		t.Error("expected First to close reader")
+	}
+}
+
+func TestAnyClosesUpstreamOnMatch(t *testing.T) {
+	src := &closableSource{}
+	if !newPipeline[int](src).Any(func(n int) bool { return n == 5 }) {
+		t.Fatal("expected match")
+	}
+	if !src.closed.Load() {
+		t.Error("expected Any to close upstream")
+	}
+}
+
+func TestAllClosesUpstreamOnMismatch(t *testing.T) {
+	src := &closableSource{}
+	if newPipeline[int](src).All(func(n int) bool { return n < 3 }) {
+		t.Fatal("expected mismatch")
+	}
+	if !src.closed.Load() {
+		t.Error("expected All to close upstream")
+	}
+}
+
+func TestCloseStopsParallelStream(t *testing.T) {
+	src := &closableSource{}
+	p := PipeMapParallelStream(newPipeline[int](src), 4, 2, func(n int) int {
+		time.Sleep(time.Millisecond)
+		return n
+	})
+	if _, ok := p.source.Next(); !ok {
+		t.Fatal("expected an element")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !src.closed.Load() {
+		t.Error("expected stream Close to close upstream")
+	}
+	deadline := time.After(time.Second)
+	for {
+		if _, ok := p.source.Next(); !ok {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("stream still producing after Close")
+		default:
+		}
+	}
+}
+
+func TestCloseUnblocksBatchReader(t *testing.T) {
+	block := make(chan int)
+	defer close(block)
+	p := PipeBatch(FromChannel(block), BatchConfig{Size: 10, MaxWait: time.Millisecond})
+	p.Close()
+	select {
+	case _, ok := <-p.source.(*batchChanSource[int]).ch:
+		if ok {
+			t.Fatal("expected no batches")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch goroutine did not stop after Close")
+	}
+}
+
+func TestBatchCloseDoesNotBlockOnAbandonedConsumer(t *testing.T) {
+	src := &closableSource{}
+	p := PipeBatch(newPipeline[int](src), BatchConfig{Size: 1, MaxWait: time.Millisecond})
+	if _, ok := p.source.Next(); !ok {
+		t.Fatal("expected a batch")
+	}
+	p.Close()
+	ch := p.source.(*batchChanSource[int]).ch
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("batch goroutine still running after Close")
+		}
+	}
+}
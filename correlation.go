@@ -0,0 +1,308 @@
+package gosplice
+
+import (
+	"math"
+	"slices"
+)
+
+// Regression is an ordinary least squares fit y = Slope·x + Intercept.
+type Regression struct {
+	N         int
+	Slope     float64
+	Intercept float64
+	R2        float64 // coefficient of determination
+	StdErr    float64 // residual standard error, √(SSE / (N-2))
+}
+
+// Predict returns Slope·x + Intercept.
+func (r Regression) Predict(x float64) float64 { return r.Slope*x + r.Intercept }
+
+// regressionFrom derives the fit from accumulated moments. Fewer than 2
+// points or constant x give a zero Regression (with N set).
+func regressionFrom(a *CorrelationAcc) Regression {
+	r := Regression{N: a.Count}
+	if a.Count < 2 || a.M2X == 0 {
+		return r
+	}
+	r.Slope = a.CoMoment / a.M2X
+	r.Intercept = a.MeanY - r.Slope*a.MeanX
+	sse := max(a.M2Y-r.Slope*a.CoMoment, 0)
+	if a.M2Y > 0 {
+		r.R2 = 1 - sse/a.M2Y
+	} else {
+		r.R2 = 1 // y is constant and the horizontal line fits it exactly
+	}
+	if a.Count > 2 {
+		r.StdErr = math.Sqrt(sse / float64(a.Count-2))
+	}
+	return r
+}
+
+// --- Pipeline aggregations ---
+
+// LinearRegressionBy fits fnY against fnX by least squares — single-pass, O(1) memory.
+func LinearRegressionBy[T any](p *Pipeline[T], fnX, fnY func(T) float64) Regression {
+	defer p.finalize()
+	a := fold(p, CorrelationAcc{}, func(a CorrelationAcc, v T) CorrelationAcc {
+		a.Add(fnX(v), fnY(v))
+		return a
+	})
+	return regressionFrom(&a)
+}
+
+// CovarianceBy returns the population covariance — single-pass, O(1) memory.
+func CovarianceBy[T any](p *Pipeline[T], fnX, fnY func(T) float64) float64 {
+	defer p.finalize()
+	a := fold(p, CorrelationAcc{}, func(a CorrelationAcc, v T) CorrelationAcc {
+		a.Add(fnX(v), fnY(v))
+		return a
+	})
+	return a.Covariance()
+}
+
+// SpearmanBy returns Spearman's rank correlation (Pearson on ranks, ties
+// get their average rank). Collects all pairs into memory — O(n log n).
+func SpearmanBy[T any](p *Pipeline[T], fnX, fnY func(T) float64) float64 {
+	defer p.finalize()
+	var xs, ys []float64
+	drain(p, func(v T) {
+		xs = append(xs, fnX(v))
+		ys = append(ys, fnY(v))
+	})
+	return Spearman(xs, ys)
+}
+
+// KendallBy returns Kendall's tau-b rank correlation (tie-corrected).
+// Collects all pairs into memory — O(n log n).
+func KendallBy[T any](p *Pipeline[T], fnX, fnY func(T) float64) float64 {
+	defer p.finalize()
+	var xs, ys []float64
+	drain(p, func(v T) {
+		xs = append(xs, fnX(v))
+		ys = append(ys, fnY(v))
+	})
+	return Kendall(xs, ys)
+}
+
+// CorrMatrix is a symmetric matrix of pairwise Pearson correlations.
+// Values[i][j] correlates Columns[i] with Columns[j]; Counts[i][j] is the
+// number of rows where both columns parsed as numbers.
+type CorrMatrix struct {
+	Columns []string
+	Values  [][]float64
+	Counts  [][]int
+}
+
+// Get returns the correlation of columns a and b, or 0 if either is unknown.
+func (m CorrMatrix) Get(a, b string) float64 {
+	i, j := slices.Index(m.Columns, a), slices.Index(m.Columns, b)
+	if i < 0 || j < 0 {
+		return 0
+	}
+	return m.Values[i][j]
+}
+
+// CorrelationMatrix computes Pearson correlations between every pair of the
+// given columns in one pass over the rows. Empty or non-numeric fields are
+// skipped pairwise (a row still counts for the pairs where both fields
+// parse), like pandas' DataFrame.corr.
+func CorrelationMatrix(p *Pipeline[Row], columns ...string) CorrMatrix {
+	defer p.finalize()
+	k := len(columns)
+	accs := make([]CorrelationAcc, k*k)
+	vals := make([]float64, k)
+	ok := make([]bool, k)
+	drain(p, func(r Row) {
+		for i, c := range columns {
+			var err error
+			vals[i], err = r.GetFloat(c)
+			ok[i] = err == nil
+		}
+		for i := 0; i < k; i++ {
+			if !ok[i] {
+				continue
+			}
+			for j := i; j < k; j++ {
+				if ok[j] {
+					accs[i*k+j].Add(vals[i], vals[j])
+				}
+			}
+		}
+	})
+
+	m := CorrMatrix{Columns: slices.Clone(columns), Values: make([][]float64, k), Counts: make([][]int, k)}
+	for i := range m.Values {
+		m.Values[i] = make([]float64, k)
+		m.Counts[i] = make([]int, k)
+	}
+	for i := 0; i < k; i++ {
+		for j := i; j < k; j++ {
+			a := &accs[i*k+j]
+			r := a.Result()
+			if i == j && a.M2X > 0 {
+				r = 1
+			}
+			m.Values[i][j], m.Values[j][i] = r, r
+			m.Counts[i][j], m.Counts[j][i] = a.Count, a.Count
+		}
+	}
+	return m
+}
+
+// --- Standalone slice functions ---
+
+// LinearRegression fits ys against xs by least squares. Extra elements of
+// the longer slice are ignored.
+func LinearRegression[N Numeric, M Numeric](xs []N, ys []M) Regression {
+	var a CorrelationAcc
+	for i := range min(len(xs), len(ys)) {
+		a.Add(float64(xs[i]), float64(ys[i]))
+	}
+	return regressionFrom(&a)
+}
+
+// Covariance returns the population covariance of paired values.
+func Covariance[N Numeric, M Numeric](xs []N, ys []M) float64 {
+	var a CorrelationAcc
+	for i := range min(len(xs), len(ys)) {
+		a.Add(float64(xs[i]), float64(ys[i]))
+	}
+	return a.Covariance()
+}
+
+// Spearman returns Spearman's rank correlation of paired values.
+// Returns 0 for fewer than 2 pairs or when either side is constant.
+func Spearman[N Numeric, M Numeric](xs []N, ys []M) float64 {
+	n := min(len(xs), len(ys))
+	if n < 2 {
+		return 0
+	}
+	return Correlation(ranks(xs[:n]), ranks(ys[:n]))
+}
+
+// ranks returns 1-based ranks, giving tied values the average of their ranks.
+func ranks[N Numeric](data []N) []float64 {
+	idx := make([]int, len(data))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(a, b int) int {
+		switch {
+		case data[a] < data[b]:
+			return -1
+		case data[a] > data[b]:
+			return 1
+		}
+		return 0
+	})
+	out := make([]float64, len(data))
+	for i := 0; i < len(idx); {
+		j := i + 1
+		for j < len(idx) && data[idx[j]] == data[idx[i]] {
+			j++
+		}
+		avg := float64(i+j+1) / 2 // ranks i+1 … j
+		for _, k := range idx[i:j] {
+			out[k] = avg
+		}
+		i = j
+	}
+	return out
+}
+
+// Kendall returns Kendall's tau-b of paired values, using Knight's
+// O(n log n) algorithm. Returns 0 for fewer than 2 pairs or when either
+// side is constant.
+func Kendall[N Numeric, M Numeric](xs []N, ys []M) float64 {
+	n := min(len(xs), len(ys))
+	if n < 2 {
+		return 0
+	}
+	type pair struct{ x, y float64 }
+	ps := make([]pair, n)
+	for i := range ps {
+		ps[i] = pair{float64(xs[i]), float64(ys[i])}
+	}
+	slices.SortFunc(ps, func(a, b pair) int {
+		switch {
+		case a.x < b.x:
+			return -1
+		case a.x > b.x:
+			return 1
+		case a.y < b.y:
+			return -1
+		case a.y > b.y:
+			return 1
+		}
+		return 0
+	})
+
+	// Pairs tied on x, and tied on both x and y.
+	var tiedX, tiedXY int
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && ps[j].x == ps[i].x {
+			j++
+		}
+		tiedX += (j - i) * (j - i - 1) / 2
+		for a := i; a < j; {
+			b := a + 1
+			for b < j && ps[b].y == ps[a].y {
+				b++
+			}
+			tiedXY += (b - a) * (b - a - 1) / 2
+			a = b
+		}
+		i = j
+	}
+
+	ys2 := make([]float64, n)
+	for i, p := range ps {
+		ys2[i] = p.y
+	}
+	swaps := countInversions(ys2, make([]float64, n))
+
+	// ys2 is now sorted: pairs tied on y.
+	var tiedY int
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && ys2[j] == ys2[i] {
+			j++
+		}
+		tiedY += (j - i) * (j - i - 1) / 2
+		i = j
+	}
+
+	total := n * (n - 1) / 2
+	denom := math.Sqrt(float64(total-tiedX) * float64(total-tiedY))
+	if denom == 0 {
+		return 0
+	}
+	return float64(total-tiedX-tiedY+tiedXY-2*swaps) / denom
+}
+
+// countInversions merge-sorts a in place (buf is scratch of the same length)
+// and returns the number of pairs i < j with a[i] > a[j].
+func countInversions(a, buf []float64) int {
+	if len(a) < 2 {
+		return 0
+	}
+	mid := len(a) / 2
+	inv := countInversions(a[:mid], buf[:mid]) + countInversions(a[mid:], buf[mid:])
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(a) {
+		if a[j] < a[i] {
+			buf[k] = a[j]
+			inv += mid - i
+			j++
+		} else {
+			buf[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(buf[k:], a[i:mid])
+	copy(buf[k:], a[j:])
+	copy(a, buf)
+	return inv
+}
@@ -0,0 +1,149 @@
+package gosplice
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+type point struct{ X, Y float64 }
+
+func px(p point) float64 { return p.X }
+func py(p point) float64 { return p.Y }
+
+func TestLinearRegressionBy_ExactFit(t *testing.T) {
+	pts := []point{{1, 5}, {2, 7}, {3, 9}, {4, 11}}
+	r := LinearRegressionBy(FromSlice(pts), px, py)
+	if !approxEqual(r.Slope, 2, eps) || !approxEqual(r.Intercept, 3, eps) ||
+		!approxEqual(r.R2, 1, eps) || !approxEqual(r.StdErr, 0, 1e-7) || r.N != 4 {
+		t.Errorf("got %+v", r)
+	}
+	if !approxEqual(r.Predict(10), 23, eps) {
+		t.Errorf("Predict(10) = %v", r.Predict(10))
+	}
+}
+
+func TestLinearRegression_Noisy(t *testing.T) {
+	// Worked by hand: Sxy=6, Sxx=10, SSE=2.4, SST=6.
+	xs := []float64{1, 2, 3, 4, 5}
+	ys := []float64{2, 4, 5, 4, 5}
+	r := LinearRegression(xs, ys)
+	if !approxEqual(r.Slope, 0.6, eps) || !approxEqual(r.Intercept, 2.2, eps) || !approxEqual(r.R2, 0.6, eps) {
+		t.Errorf("got %+v", r)
+	}
+	if !approxEqual(r.StdErr, math.Sqrt(2.4/3), eps) {
+		t.Errorf("stderr = %v", r.StdErr)
+	}
+	if (LinearRegression([]int{1}, []int{1}) != Regression{N: 1}) {
+		t.Error("single point should give an empty fit")
+	}
+}
+
+func TestCovariance(t *testing.T) {
+	xs := []float64{1, 2, 3, 4}
+	ys := []float64{2, 4, 6, 9}
+	want := 0.0
+	for i := range xs {
+		want += (xs[i] - 2.5) * (ys[i] - 5.25)
+	}
+	want /= 4
+	if got := Covariance(xs, ys); !approxEqual(got, want, eps) {
+		t.Errorf("Covariance = %v, want %v", got, want)
+	}
+	pts := []point{{1, 2}, {2, 4}, {3, 6}, {4, 9}}
+	if got := CovarianceBy(FromSlice(pts), px, py); !approxEqual(got, want, eps) {
+		t.Errorf("CovarianceBy = %v, want %v", got, want)
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	// Monotonic but non-linear: Spearman is 1 while Pearson is not.
+	xs := []float64{1, 2, 3, 4, 5}
+	ys := []float64{1, 8, 27, 64, 125}
+	if got := Spearman(xs, ys); !approxEqual(got, 1, eps) {
+		t.Errorf("monotonic = %v", got)
+	}
+	// With ties: ranks [1 2.5 2.5 4 5] and [1 4 2.5 2.5 5] give r = 7.25/9.5.
+	if got := Spearman([]int{1, 2, 2, 3, 4}, []int{1, 3, 2, 2, 5}); !approxEqual(got, 7.25/9.5, 1e-12) {
+		t.Errorf("ties = %v", got)
+	}
+	pts := []point{{1, 5}, {2, 4}, {3, 3}}
+	if got := SpearmanBy(FromSlice(pts), px, py); !approxEqual(got, -1, eps) {
+		t.Errorf("SpearmanBy = %v", got)
+	}
+}
+
+// bruteKendall is the O(n²) definition of tau-b.
+func bruteKendall(xs, ys []float64) float64 {
+	var conc, disc, tx, ty int
+	for i := range xs {
+		for j := i + 1; j < len(xs); j++ {
+			dx, dy := xs[i]-xs[j], ys[i]-ys[j]
+			switch {
+			case dx == 0 && dy == 0:
+			case dx == 0:
+				tx++
+			case dy == 0:
+				ty++
+			case (dx > 0) == (dy > 0):
+				conc++
+			default:
+				disc++
+			}
+		}
+	}
+	return float64(conc-disc) / math.Sqrt(float64(conc+disc+tx)*float64(conc+disc+ty))
+}
+
+func TestKendall_MatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for trial := 0; trial < 20; trial++ {
+		n := 2 + r.Intn(200)
+		xs, ys := make([]float64, n), make([]float64, n)
+		for i := range xs {
+			xs[i] = float64(r.Intn(10)) // plenty of ties
+			ys[i] = float64(r.Intn(10)) + xs[i]/2
+		}
+		if got, want := Kendall(xs, ys), bruteKendall(xs, ys); !approxEqual(got, want, 1e-12) {
+			t.Fatalf("n=%d: got %v, want %v", n, got, want)
+		}
+	}
+	if got := Kendall([]int{1, 1, 1}, []int{1, 2, 3}); got != 0 {
+		t.Errorf("constant x = %v", got)
+	}
+	pts := []point{{1, 3}, {2, 2}, {3, 1}}
+	if got := KendallBy(FromSlice(pts), px, py); !approxEqual(got, -1, eps) {
+		t.Errorf("KendallBy = %v", got)
+	}
+}
+
+func TestCorrelationMatrix(t *testing.T) {
+	input := "area,price,rooms,note\n" +
+		"50,100,2,a\n" +
+		"60,120,3,b\n" +
+		"70,140,,c\n" +
+		"80,160,3,d\n" +
+		"90,,4,e\n"
+	m := CorrelationMatrix(FromCSVRows(strings.NewReader(input), CSVConfig{Header: true}), "area", "price", "rooms", "note")
+
+	if got := m.Get("area", "price"); !approxEqual(got, 1, eps) {
+		t.Errorf("area/price = %v", got)
+	}
+	if m.Get("price", "area") != m.Get("area", "price") {
+		t.Error("matrix not symmetric")
+	}
+	wantRooms := Correlation([]float64{50, 60, 80, 90}, []float64{2, 3, 3, 4})
+	if got := m.Get("area", "rooms"); !approxEqual(got, wantRooms, eps) {
+		t.Errorf("area/rooms = %v, want %v", got, wantRooms)
+	}
+	if m.Counts[0][1] != 4 || m.Counts[0][2] != 4 || m.Counts[0][0] != 5 {
+		t.Errorf("counts = %v", m.Counts)
+	}
+	if m.Get("area", "area") != 1 || m.Get("note", "note") != 0 || m.Counts[3][3] != 0 {
+		t.Errorf("diagonal: area %v, note %v", m.Get("area", "area"), m.Get("note", "note"))
+	}
+	if m.Get("area", "missing") != 0 {
+		t.Error("unknown column should give 0")
+	}
+}
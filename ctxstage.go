@@ -0,0 +1,230 @@
+package gosplice
+
+import (
+	"context"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Context-aware stages
+// ---------------------------------------------------------------------------
+
+// The ctx stages pass the pipeline's context (Background if none) to fn, so
+// an HTTP call or query in flight is cancelled with the pipeline instead of
+// running on after WithTimeout or WithContext gave up. With
+// WithElementTimeout each call gets its own deadline as well.
+//
+// Errors go through the error handler as in PipeMapErr; an error that
+// happens because the pipeline's context is done is not reported, the
+// stage just ends.
+
+// PipeMapCtx is PipeMapErr for functions that take a context.
+//
+//	pages := gs.PipeMapCtx(
+//	    gs.FromSlice(urls).WithTimeout(time.Minute).WithElementTimeout(5*time.Second),
+//	    func(ctx context.Context, url string) ([]byte, error) { return fetch(ctx, url) },
+//	)
+func PipeMapCtx[T any, U any](p *Pipeline[T], fn func(context.Context, T) (U, error)) *Pipeline[U] {
+	return &Pipeline[U]{
+		source:  newCtxMapSource(p, fn, p.hooks.hasElement()),
+		hooks:   inheritHooks[U](p.hooks),
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+// PipeFilterCtx keeps elements for which fn returns true. An element whose
+// call fails is dropped, after the error handler has seen it.
+func PipeFilterCtx[T any](p *Pipeline[T], fn func(context.Context, T) (bool, error)) *Pipeline[T] {
+	keep := func(ctx context.Context, v T) (kept[T], error) {
+		ok, err := fn(ctx, v)
+		return kept[T]{v: v, ok: ok}, err
+	}
+	return &Pipeline[T]{
+		source:  &filterKeptSource[T]{inner: newCtxMapSource(p, keep, false)},
+		hooks:   p.hooks,
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+// PipeFlatMapCtx is PipeFlatMap for fallible functions that take a context.
+func PipeFlatMapCtx[T any, U any](p *Pipeline[T], fn func(context.Context, T) ([]U, error)) *Pipeline[U] {
+	return &Pipeline[U]{
+		source:  &flattenSource[U]{inner: newCtxMapSource(p, fn, p.hooks.hasElement())},
+		hooks:   inheritHooks[U](p.hooks),
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+// PipeMapParallelCtx is PipeMapParallelErr for functions that take a
+// context. Once the context is done, calls not yet started are skipped and
+// the output ends at the first element they failed.
+func PipeMapParallelCtx[T any, U any](p *Pipeline[T], workers int, fn func(context.Context, T) (U, error)) *Pipeline[U] {
+	ctx, timeout := stageCtx(p), p.hooks.ElementTimeout
+	return mapParallelErr(p, workers, func(v T) (U, error) {
+		return callCtx(ctx, timeout, fn, v)
+	}, ctx)
+}
+
+// PipeMapParallelStreamCtx is PipeMapParallelStream for fallible functions
+// that take a context. The context is also cancelled when the consumer
+// stops early (Take, First, Close), so in-flight calls end with the stream.
+// Errors go through the error handler in element order; retries are not
+// supported and the element is skipped.
+func PipeMapParallelStreamCtx[T any, U any](p *Pipeline[T], workers int, bufSize int, fn func(context.Context, T) (U, error)) *Pipeline[U] {
+	hooks, timeout := p.hooks, p.hooks.ElementTimeout
+	results := mapParallelStream(p, StreamConfig{Workers: workers, Buffer: bufSize},
+		func(ctx context.Context, v T) ctxResult[T, U] {
+			out, err := callCtx(ctx, timeout, fn, v)
+			return ctxResult[T, U]{in: v, out: out, err: err}
+		})
+	return &Pipeline[U]{
+		source:  &ctxResultSource[T, U]{inner: results.source, hooks: hooks, ctx: p.ctx},
+		hooks:   inheritHooks[U](hooks),
+		ctx:     results.ctx,
+		cancel:  results.cancel,
+		stop:    results.stop,
+		ctxNoop: results.ctxNoop,
+	}
+}
+
+// stageCtx returns the context the ctx stages pass to fn.
+func stageCtx[T any](p *Pipeline[T]) context.Context {
+	if p.ctx != nil {
+		return p.ctx
+	}
+	return context.Background()
+}
+
+// callCtx calls fn with ctx, under its own deadline when timeout > 0. A
+// call that returns no error after that deadline still fails with
+// context.DeadlineExceeded, so the timeout holds even if fn ignores ctx.
+func callCtx[T any, U any](ctx context.Context, timeout time.Duration, fn func(context.Context, T) (U, error), v T) (U, error) {
+	if timeout <= 0 {
+		return fn(ctx, v)
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out, err := fn(cctx, v)
+	if err == nil && cctx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		var zero U
+		return zero, context.DeadlineExceeded
+	}
+	return out, err
+}
+
+func newCtxMapSource[T any, U any](p *Pipeline[T], fn func(context.Context, T) (U, error), fireHooks bool) *mapErrSource[T, U] {
+	ctx, timeout := stageCtx(p), p.hooks.ElementTimeout
+	return &mapErrSource[T, U]{
+		inner: p.source,
+		fn:    func(v T) (U, error) { return callCtx(ctx, timeout, fn, v) },
+		hooks: p.hooks, hasHooks: fireHooks,
+		hasErr: p.hooks.hasError(), maxRetries: p.hooks.MaxRetries,
+		ctx: ctx,
+	}
+}
+
+// kept is an element with its filter verdict.
+type kept[T any] struct {
+	v  T
+	ok bool
+}
+
+type filterKeptSource[T any] struct {
+	inner *mapErrSource[T, kept[T]]
+}
+
+func (s *filterKeptSource[T]) Next() (T, bool) {
+	for {
+		k, ok := s.inner.Next()
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		if k.ok {
+			return k.v, true
+		}
+	}
+}
+
+func (s *filterKeptSource[T]) Err() error { return s.inner.Err() }
+
+func (s *filterKeptSource[T]) Close() error { return s.inner.Close() }
+
+// flattenSource yields the elements of each slice from inner in turn.
+type flattenSource[U any] struct {
+	inner interface {
+		Source[[]U]
+		Err() error
+		Close() error
+	}
+	buf []U
+	idx int
+}
+
+func (s *flattenSource[U]) Next() (U, bool) {
+	for s.idx >= len(s.buf) {
+		buf, ok := s.inner.Next()
+		if !ok {
+			var zero U
+			return zero, false
+		}
+		s.buf, s.idx = buf, 0
+	}
+	v := s.buf[s.idx]
+	s.idx++
+	return v, true
+}
+
+func (s *flattenSource[U]) Err() error { return s.inner.Err() }
+
+func (s *flattenSource[U]) Close() error { return s.inner.Close() }
+
+// ctxResult carries one call of PipeMapParallelStreamCtx with its input,
+// for the error handler.
+type ctxResult[T any, U any] struct {
+	in  T
+	out U
+	err error
+}
+
+// ctxResultSource unwraps ctxResults, routing errors through the error
+// handler in element order.
+type ctxResultSource[T any, U any] struct {
+	inner Source[ctxResult[T, U]]
+	hooks *Hooks[T]
+	ctx   context.Context
+	done  bool
+}
+
+func (s *ctxResultSource[T, U]) Next() (U, bool) {
+	var zero U
+	for !s.done {
+		r, ok := s.inner.Next()
+		if !ok {
+			return zero, false
+		}
+		if r.err == nil {
+			return r.out, true
+		}
+		if s.ctx != nil && s.ctx.Err() != nil {
+			break
+		}
+		if s.hooks.handleError(r.err, r.in, 1) == Abort {
+			s.done = true
+		}
+	}
+	return zero, false
+}
+
+func (s *ctxResultSource[T, U]) Err() error { return innerErr(s.inner) }
+
+func (s *ctxResultSource[T, U]) Close() error { return closeSource(s.inner) }
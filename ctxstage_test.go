@@ -0,0 +1,220 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowCall waits d or until ctx is done, like a network call.
+func slowCall(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestPipeMapCtx_CancelsInFlightCall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var handled atomic.Int32
+	p := PipeMapCtx(
+		FromSlice([]int{1, 2, 3}).WithContext(ctx).
+			WithErrorHook(func(error, int) { handled.Add(1) }),
+		func(ctx context.Context, v int) (int, error) {
+			if v == 2 {
+				cancel()
+				return 0, slowCall(ctx, time.Hour)
+			}
+			return v * 10, nil
+		})
+	start := time.Now()
+	out := p.Collect()
+	if time.Since(start) > time.Second {
+		t.Fatal("call was not cancelled")
+	}
+	if !slices.Equal(out, []int{10}) {
+		t.Errorf("got %v", out)
+	}
+	if handled.Load() != 0 {
+		t.Errorf("cancellation reported %d times to the error hooks", handled.Load())
+	}
+	if !errors.Is(p.Err(), context.Canceled) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
+
+func TestPipeMapCtx_ElementTimeout(t *testing.T) {
+	var timedOut []int
+	out := PipeMapCtx(
+		FromSlice([]int{1, 50, 2, 60}).
+			WithElementTimeout(20*time.Millisecond).
+			WithErrorHook(func(err error, v int) {
+				if errors.Is(err, context.DeadlineExceeded) {
+					timedOut = append(timedOut, v)
+				}
+			}),
+		func(ctx context.Context, v int) (int, error) {
+			// v is the call's duration in ms.
+			if err := slowCall(ctx, time.Duration(v)*time.Millisecond); err != nil {
+				return 0, err
+			}
+			return v, nil
+		}).Collect()
+	if !slices.Equal(out, []int{1, 2}) || !slices.Equal(timedOut, []int{50, 60}) {
+		t.Errorf("out %v, timed out %v", out, timedOut)
+	}
+}
+
+func TestPipeMapCtx_TimeoutHoldsWhenFnIgnoresCtx(t *testing.T) {
+	var errs []error
+	out := PipeMapCtx(
+		FromSlice([]int{1}).
+			WithElementTimeout(5*time.Millisecond).
+			WithErrorHook(func(err error, _ int) { errs = append(errs, err) }),
+		func(_ context.Context, v int) (int, error) {
+			time.Sleep(20 * time.Millisecond)
+			return v, nil
+		}).Collect()
+	if len(out) != 0 || len(errs) != 1 || errs[0] != context.DeadlineExceeded {
+		t.Errorf("out %v, errs %v", out, errs)
+	}
+}
+
+func TestPipeMapCtx_RetryGetsFreshDeadline(t *testing.T) {
+	var calls atomic.Int32
+	out := PipeMapCtx(
+		FromSlice([]int{7}).
+			WithElementTimeout(20*time.Millisecond).
+			WithErrorHandler(func(err error, _ int, attempt int) ErrorAction {
+				if errors.Is(err, context.DeadlineExceeded) {
+					return Retry
+				}
+				return Skip
+			}),
+		func(ctx context.Context, v int) (int, error) {
+			if calls.Add(1) == 1 {
+				return 0, slowCall(ctx, time.Hour)
+			}
+			return v, slowCall(ctx, time.Millisecond)
+		}).Collect()
+	if !slices.Equal(out, []int{7}) || calls.Load() != 2 {
+		t.Errorf("out %v after %d calls", out, calls.Load())
+	}
+}
+
+func TestPipeFilterCtx(t *testing.T) {
+	errOdd := errors.New("cannot check 5")
+	var failed []int
+	out := PipeFilterCtx(
+		FromSlice([]int{1, 2, 3, 4, 5, 6}).
+			WithErrorHook(func(err error, v int) { failed = append(failed, v) }),
+		func(ctx context.Context, v int) (bool, error) {
+			if v == 5 {
+				return true, errOdd
+			}
+			return v%2 == 0, ctx.Err()
+		}).Collect()
+	if !slices.Equal(out, []int{2, 4, 6}) || !slices.Equal(failed, []int{5}) {
+		t.Errorf("out %v, failed %v", out, failed)
+	}
+}
+
+func TestPipeFlatMapCtx(t *testing.T) {
+	out := PipeFlatMapCtx(FromSlice([]int{1, 0, 2, 3}), func(ctx context.Context, v int) ([]int, error) {
+		if v == 3 {
+			return nil, errors.New("skip me")
+		}
+		return slices.Repeat([]int{v}, v), nil
+	}).Collect()
+	if !slices.Equal(out, []int{1, 2, 2}) {
+		t.Errorf("got %v", out)
+	}
+}
+
+func TestPipeMapParallelCtx(t *testing.T) {
+	var mu sync.Mutex
+	var timedOut []int
+	out := PipeMapParallelCtx(
+		FromSlice([]int{1, 2, 300, 4}).
+			WithElementTimeout(30*time.Millisecond).
+			WithErrorHook(func(err error, v int) {
+				mu.Lock()
+				timedOut = append(timedOut, v)
+				mu.Unlock()
+			}),
+		4, func(ctx context.Context, v int) (int, error) {
+			return v, slowCall(ctx, time.Duration(v)*time.Millisecond)
+		}).Collect()
+	if !slices.Equal(out, []int{1, 2, 4}) || !slices.Equal(timedOut, []int{300}) {
+		t.Errorf("out %v, timed out %v", out, timedOut)
+	}
+}
+
+func TestPipeMapParallelCtx_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var handled atomic.Int32
+	p := PipeMapParallelCtx(
+		FromSlice([]int{1, 2, 3, 4}).WithContext(ctx).
+			WithErrorHook(func(error, int) { handled.Add(1) }),
+		2, func(ctx context.Context, v int) (int, error) {
+			if v == 1 {
+				return v, nil
+			}
+			cancel()
+			return 0, slowCall(ctx, time.Hour)
+		})
+	out := p.Collect()
+	if !slices.Equal(out, []int{1}) || handled.Load() != 0 {
+		t.Errorf("out %v, %d errors reported", out, handled.Load())
+	}
+	if !errors.Is(p.Err(), context.Canceled) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
+
+func TestPipeMapParallelStreamCtx_EarlyStopCancelsCalls(t *testing.T) {
+	var cancelled atomic.Int32
+	out := PipeMapParallelStreamCtx(FromSlice(makeRange(20)), 4, 4, func(ctx context.Context, v int) (int, error) {
+		if v < 2 {
+			return v, nil
+		}
+		err := slowCall(ctx, time.Hour)
+		if err != nil {
+			cancelled.Add(1)
+		}
+		return v, err
+	}).Take(2).Collect()
+	if !slices.Equal(out, []int{0, 1}) {
+		t.Errorf("got %v", out)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for cancelled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if cancelled.Load() == 0 {
+		t.Error("in-flight calls were not cancelled")
+	}
+}
+
+func TestPipeMapParallelStreamCtx_ErrorsInOrder(t *testing.T) {
+	var failed []int
+	out := PipeMapParallelStreamCtx(
+		FromSlice(makeRange(10)).
+			WithElementTimeout(20*time.Millisecond).
+			WithErrorHook(func(err error, v int) { failed = append(failed, v) }),
+		4, 4, func(ctx context.Context, v int) (int, error) {
+			if v%3 == 0 {
+				return 0, slowCall(ctx, time.Hour)
+			}
+			return v, nil
+		}).Collect()
+	if !slices.Equal(out, []int{1, 2, 4, 5, 7, 8}) || !slices.Equal(failed, []int{0, 3, 6, 9}) {
+		t.Errorf("out %v, failed %v", out, failed)
+	}
+}
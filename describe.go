@@ -0,0 +1,310 @@
+package gosplice
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// ColumnType is the type DescribeRows infers for a column: the narrowest
+// type every non-empty value parses as.
+type ColumnType uint8
+
+const (
+	ColumnEmpty  ColumnType = iota // no non-empty values
+	ColumnInt                      // every value parses with strconv.ParseInt
+	ColumnFloat                    // every value parses with strconv.ParseFloat
+	ColumnBool                     // every value parses with strconv.ParseBool
+	ColumnString                   // anything else
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case ColumnEmpty:
+		return "empty"
+	case ColumnInt:
+		return "int"
+	case ColumnFloat:
+		return "float"
+	case ColumnBool:
+		return "bool"
+	case ColumnString:
+		return "string"
+	}
+	return "ColumnType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// ColumnSummary profiles one column of a Row pipeline.
+type ColumnSummary struct {
+	Name     string
+	Index    int // position in the header, for ordering
+	Type     ColumnType
+	Count    int // non-empty values
+	Nulls    int // empty or missing values
+	Distinct int // distinct non-empty values
+
+	// Approximate is set once the column had more than DistinctLimit
+	// distinct values: Distinct is then a HyperLogLog estimate and Top
+	// counts are upper bounds from a CountMinSketch.
+	Approximate bool
+
+	Stats  Stats                 // int and float columns only
+	Top    []HeavyHitter[string] // bool and string columns only; most frequent first
+	MinLen int                   // in runes, over non-empty values
+	MaxLen int
+}
+
+// Defaults for DescribeRowsConfig.
+const (
+	DefaultDescribeTopN          = 5
+	DefaultDescribeDistinctLimit = 10_000
+)
+
+// DescribeRowsConfig tunes DescribeRowsWith. Zero fields use the defaults.
+type DescribeRowsConfig struct {
+	TopN          int // frequent values kept per string column
+	DistinctLimit int // distinct values counted exactly per column before switching to sketches
+}
+
+// DescribeRows profiles every column of a Row pipeline in a single pass,
+// like pandas' DataFrame.describe(include="all"): inferred type, counts,
+// distinct values, Stats for numeric columns, the most frequent values of
+// string columns and value lengths. Columns are named by the header, or
+// "0", "1", ... without one; a repeated header name gets a suffix, so two
+// "id" columns come out as "id" and "id_2". Print the result with
+// WriteSummaryTable.
+//
+// Memory per column is bounded: values are counted exactly up to
+// DefaultDescribeDistinctLimit distinct values, then by sketches; quartiles
+// come from a QuantileSketch (exact for small inputs).
+func DescribeRows(p *Pipeline[Row]) map[string]ColumnSummary {
+	return DescribeRowsWith(p, DescribeRowsConfig{})
+}
+
+// DescribeRowsWith is DescribeRows with explicit limits.
+func DescribeRowsWith(p *Pipeline[Row], cfg DescribeRowsConfig) map[string]ColumnSummary {
+	defer p.finalize()
+	if cfg.TopN <= 0 {
+		cfg.TopN = DefaultDescribeTopN
+	}
+	if cfg.DistinctLimit <= 0 {
+		cfg.DistinctLimit = DefaultDescribeDistinctLimit
+	}
+
+	var cols []*columnProfile
+	rows := 0
+	drain(p, func(r Row) {
+		rows++
+		n := len(r.fields)
+		if r.header != nil {
+			n = min(n, len(r.header))
+		}
+		for len(cols) < max(n, len(r.header)) {
+			i := len(cols)
+			name := strconv.Itoa(i)
+			if i < len(r.header) {
+				name = r.header[i]
+			}
+			cols = append(cols, newColumnProfile(name, i))
+		}
+		for i, f := range r.fields[:n] {
+			if f != "" {
+				cols[i].add(f, cfg)
+			}
+		}
+	})
+
+	uniqueColumnNames(cols)
+	out := make(map[string]ColumnSummary, len(cols))
+	for _, c := range cols {
+		out[c.name] = c.summary(rows, cfg.TopN)
+	}
+	return out
+}
+
+// uniqueColumnNames renames repeated header names so no column overwrites
+// another in the result: the first keeps its name, later ones become
+// name_2, name_3, ..., skipping names already in the header.
+func uniqueColumnNames(cols []*columnProfile) {
+	taken := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		taken[c.name] = true
+	}
+	seen := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		if !seen[c.name] {
+			seen[c.name] = true
+			continue
+		}
+		for k := 2; ; k++ {
+			name := c.name + "_" + strconv.Itoa(k)
+			if !taken[name] {
+				taken[name] = true
+				c.name = name
+				break
+			}
+		}
+	}
+}
+
+// columnProfile is the running state for one column.
+type columnProfile struct {
+	name                   string
+	index                  int
+	count                  int
+	isInt, isFloat, isBool bool
+	stats                  StatsAcc // while isFloat
+	minLen, maxLen         int
+
+	exact map[string]uint64 // until DistinctLimit distinct values
+	hll   *HyperLogLog      // afterwards
+	top   *HeavyHitters[string]
+}
+
+func newColumnProfile(name string, index int) *columnProfile {
+	return &columnProfile{
+		name: name, index: index,
+		isInt: true, isFloat: true, isBool: true,
+		exact: make(map[string]uint64),
+	}
+}
+
+func (c *columnProfile) add(s string, cfg DescribeRowsConfig) {
+	c.count++
+	if c.isInt {
+		_, err := strconv.ParseInt(s, 10, 64)
+		c.isInt = err == nil
+	}
+	if c.isFloat {
+		if x, err := strconv.ParseFloat(s, 64); err == nil {
+			c.stats.Add(x)
+		} else {
+			c.isFloat = false
+			c.stats = StatsAcc{} // release the sketch
+		}
+	}
+	if c.isBool {
+		_, err := strconv.ParseBool(s)
+		c.isBool = err == nil
+	}
+
+	n := utf8.RuneCountInString(s)
+	if c.count == 1 || n < c.minLen {
+		c.minLen = n
+	}
+	c.maxLen = max(c.maxLen, n)
+
+	if c.exact != nil {
+		c.exact[s]++
+		if len(c.exact) > cfg.DistinctLimit {
+			c.toSketches(cfg.TopN)
+		}
+		return
+	}
+	c.hll.Add(s)
+	c.top.Add(s)
+}
+
+// toSketches replays the exact counts into a HyperLogLog and HeavyHitters
+// and drops the map.
+func (c *columnProfile) toSketches(topN int) {
+	c.hll = NewHyperLogLog(DefaultHLLPrecision)
+	c.top = NewHeavyHitters[string](topN, 0.001, 0.01)
+	for s, n := range c.exact {
+		c.hll.Add(s)
+		c.top.addN(s, n)
+	}
+	c.exact = nil
+}
+
+func (c *columnProfile) summary(rows, topN int) ColumnSummary {
+	s := ColumnSummary{
+		Name:   c.name,
+		Index:  c.index,
+		Count:  c.count,
+		Nulls:  rows - c.count,
+		MinLen: c.minLen,
+		MaxLen: c.maxLen,
+	}
+	switch {
+	case c.count == 0:
+		s.Type = ColumnEmpty
+	case c.isInt:
+		s.Type = ColumnInt
+	case c.isFloat:
+		s.Type = ColumnFloat
+	case c.isBool:
+		s.Type = ColumnBool
+	default:
+		s.Type = ColumnString
+	}
+	if s.Type == ColumnInt || s.Type == ColumnFloat {
+		s.Stats = c.stats.Result()
+	}
+
+	if c.exact == nil {
+		s.Approximate = true
+		s.Distinct = c.hll.Count()
+		if s.Type == ColumnBool || s.Type == ColumnString {
+			s.Top = c.top.Top()
+		}
+		return s
+	}
+	s.Distinct = len(c.exact)
+	if s.Type == ColumnBool || s.Type == ColumnString {
+		s.Top = make([]HeavyHitter[string], 0, len(c.exact))
+		for v, n := range c.exact {
+			s.Top = append(s.Top, HeavyHitter[string]{Key: v, Count: n})
+		}
+		slices.SortFunc(s.Top, func(a, b HeavyHitter[string]) int {
+			if a.Count != b.Count {
+				return cmp.Compare(b.Count, a.Count)
+			}
+			return strings.Compare(a.Key, b.Key)
+		})
+		s.Top = s.Top[:min(len(s.Top), topN)]
+	}
+	return s
+}
+
+// WriteSummaryTable writes summaries as an aligned text table, one line per
+// column in header order. Numeric cells are blank for non-numeric columns,
+// and approximate distinct counts are prefixed with "~".
+func WriteSummaryTable(w io.Writer, summaries map[string]ColumnSummary) error {
+	cols := make([]ColumnSummary, 0, len(summaries))
+	for _, s := range summaries {
+		cols = append(cols, s)
+	}
+	slices.SortFunc(cols, func(a, b ColumnSummary) int { return cmp.Compare(a.Index, b.Index) })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "column\ttype\tcount\tnulls\tdistinct\tmean\tstd\tmin\t50%\tmax\tlen\ttop")
+	for _, s := range cols {
+		distinct := strconv.Itoa(s.Distinct)
+		if s.Approximate {
+			distinct = "~" + distinct
+		}
+		var num [5]string
+		if s.Type == ColumnInt || s.Type == ColumnFloat {
+			for i, x := range []float64{s.Stats.Mean, s.Stats.StdDev, s.Stats.Min, s.Stats.Median, s.Stats.Max} {
+				num[i] = strconv.FormatFloat(x, 'g', 6, 64)
+			}
+		}
+		var length, top string
+		if s.Count > 0 {
+			length = fmt.Sprintf("%d-%d", s.MinLen, s.MaxLen)
+		}
+		if len(s.Top) > 0 {
+			top = fmt.Sprintf("%s (%d)", s.Top[0].Key, s.Top[0].Count)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.Name, s.Type, s.Count, s.Nulls, distinct,
+			num[0], num[1], num[2], num[3], num[4], length, top)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,150 @@
+package gosplice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const describeCSV = "id,price,active,city,notes\n" +
+	"1,10.5,true,Paris,\n" +
+	"2,20,false,Berlin,x\n" +
+	"3,,true,Paris,\n" +
+	"4,30.5,yes,Rome,\n" +
+	"5,40,true,Paris\n"
+
+func TestDescribeRows(t *testing.T) {
+	got := DescribeRows(FromCSVRows(strings.NewReader(describeCSV), CSVConfig{Header: true}))
+	if len(got) != 5 {
+		t.Fatalf("got %d columns", len(got))
+	}
+
+	id := got["id"]
+	if id.Type != ColumnInt || id.Count != 5 || id.Nulls != 0 || id.Distinct != 5 || id.Top != nil {
+		t.Errorf("id: %+v", id)
+	}
+	if id.Stats.Mean != 3 || id.Stats.Median != 3 || id.Stats.Max != 5 {
+		t.Errorf("id stats: %+v", id.Stats)
+	}
+
+	price := got["price"]
+	if price.Type != ColumnFloat || price.Count != 4 || price.Nulls != 1 {
+		t.Errorf("price: %+v", price)
+	}
+	if want := Describe([]float64{10.5, 20, 30.5, 40}); price.Stats != want {
+		t.Errorf("price stats: %+v, want %+v", price.Stats, want)
+	}
+
+	// "yes" is not a strconv bool, so the column falls back to string.
+	active := got["active"]
+	if active.Type != ColumnString || active.Distinct != 3 || active.MinLen != 3 || active.MaxLen != 5 {
+		t.Errorf("active: %+v", active)
+	}
+
+	city := got["city"]
+	if city.Type != ColumnString || len(city.Top) != 3 || city.Top[0] != (HeavyHitter[string]{Key: "Paris", Count: 3}) {
+		t.Errorf("city: %+v", city)
+	}
+	// Ties are broken by value.
+	if city.Top[1].Key != "Berlin" || city.Top[2].Key != "Rome" {
+		t.Errorf("city top order: %+v", city.Top)
+	}
+	if city.Stats != (Stats{}) {
+		t.Error("string column should have no stats")
+	}
+
+	// The last row is short: its missing field counts as null.
+	notes := got["notes"]
+	if notes.Count != 1 || notes.Nulls != 4 || notes.Index != 4 {
+		t.Errorf("notes: %+v", notes)
+	}
+}
+
+func TestDescribeRows_TypesAndNoHeader(t *testing.T) {
+	in := "t,1\nf,0\nT,1,extra\n,\n"
+	got := DescribeRows(FromCSVRows(strings.NewReader(in), CSVConfig{}))
+	if got["0"].Type != ColumnBool || got["0"].Nulls != 1 {
+		t.Errorf("col 0: %+v", got["0"])
+	}
+	// 1/0 parse as both int and bool; int wins.
+	if got["1"].Type != ColumnInt || got["1"].Stats.Sum != 2 {
+		t.Errorf("col 1: %+v", got["1"])
+	}
+	// A column that only appears in a later row still counts earlier rows as null.
+	if got["2"].Count != 1 || got["2"].Nulls != 3 {
+		t.Errorf("col 2: %+v", got["2"])
+	}
+	if got["1"].Nulls != 1 || got["0"].Distinct != 3 {
+		t.Errorf("nulls/distinct: %+v %+v", got["1"], got["0"])
+	}
+
+	empty := DescribeRows(FromCSVRows(strings.NewReader("a,b\n,\n"), CSVConfig{Header: true}))
+	if empty["a"].Type != ColumnEmpty || empty["a"].Nulls != 1 {
+		t.Errorf("empty column: %+v", empty["a"])
+	}
+}
+
+func TestDescribeRows_DuplicateHeader(t *testing.T) {
+	in := "id,id,id_2,id\n1,x,true,\n2,y,false,\n"
+	got := DescribeRows(FromCSVRows(strings.NewReader(in), CSVConfig{Header: true}))
+	if len(got) != 4 {
+		t.Fatalf("got %d columns: %v", len(got), got)
+	}
+	want := map[string]ColumnType{"id": ColumnInt, "id_3": ColumnString, "id_2": ColumnBool, "id_4": ColumnEmpty}
+	for name, typ := range want {
+		if c := got[name]; c.Type != typ || c.Name != name {
+			t.Errorf("%s: %+v", name, c)
+		}
+	}
+}
+
+func TestDescribeRowsWith_SwitchesToSketches(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("user,kind\n")
+	for i := 0; i < 20_000; i++ {
+		kind := "view"
+		if i%4 == 0 {
+			kind = "click"
+		}
+		fmt.Fprintf(&b, "u%d,%s\n", i, kind)
+	}
+	got := DescribeRowsWith(FromCSVRows(strings.NewReader(b.String()), CSVConfig{Header: true}),
+		DescribeRowsConfig{TopN: 2, DistinctLimit: 1000})
+
+	user := got["user"]
+	if !user.Approximate || user.Distinct < 19_000 || user.Distinct > 21_000 {
+		t.Errorf("user: approximate=%v distinct=%d", user.Approximate, user.Distinct)
+	}
+	if len(user.Top) != 2 || user.Count != 20_000 {
+		t.Errorf("user top: %+v", user.Top)
+	}
+
+	kind := got["kind"]
+	if kind.Approximate || kind.Distinct != 2 {
+		t.Errorf("kind: %+v", kind)
+	}
+	if kind.Top[0] != (HeavyHitter[string]{Key: "view", Count: 15_000}) {
+		t.Errorf("kind top: %+v", kind.Top)
+	}
+}
+
+func TestWriteSummaryTable(t *testing.T) {
+	got := DescribeRows(FromCSVRows(strings.NewReader(describeCSV), CSVConfig{Header: true}))
+	var b strings.Builder
+	if err := WriteSummaryTable(&b, got); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines:\n%s", len(lines), b.String())
+	}
+	if !strings.HasPrefix(lines[0], "column") || !strings.HasPrefix(lines[1], "id ") || !strings.HasPrefix(lines[5], "notes ") {
+		t.Errorf("unexpected layout:\n%s", b.String())
+	}
+	if f := strings.Fields(lines[2]); f[0] != "price" || f[1] != "float" || f[5] != "25.25" {
+		t.Errorf("price line: %q", lines[2])
+	}
+	if !strings.Contains(lines[4], "Paris (3)") {
+		t.Errorf("city line: %q", lines[4])
+	}
+}
@@ -0,0 +1,257 @@
+package gosplice
+
+import (
+	"container/list"
+	"math"
+	"time"
+)
+
+// DistinctMode selects how PipeDistinctBy remembers keys it has seen.
+type DistinctMode int
+
+const (
+	DistinctExact DistinctMode = iota // every key, forever (like PipeDistinct)
+	DistinctLRU                       // the MaxKeys most recently seen keys
+	DistinctTTL                       // keys first seen within Window
+	DistinctBloom                     // Bloom filter sized for MaxKeys at FalsePositiveRate
+)
+
+// DefaultDistinctKeys is the MaxKeys used by DistinctLRU and DistinctBloom when unset.
+const DefaultDistinctKeys = 100_000
+
+// DefaultDistinctWindow is the Window used by DistinctTTL when unset.
+const DefaultDistinctWindow = time.Minute
+
+// DistinctConfig controls PipeDistinctBy. The zero value deduplicates
+// exactly, remembering every key. Each mode reads only its own fields.
+type DistinctConfig[T any] struct {
+	// Mode selects how seen keys are remembered. Defaults to DistinctExact.
+	Mode DistinctMode
+
+	// MaxKeys is the LRU capacity for DistinctLRU, and the expected number
+	// of distinct keys for DistinctBloom. Defaults to DefaultDistinctKeys.
+	MaxKeys int
+
+	// Window is how long a key suppresses duplicates after it is first
+	// emitted (DistinctTTL). A key seen again after Window is emitted again
+	// and starts a new window. Zero or negative means DefaultDistinctWindow.
+	Window time.Duration
+
+	// FalsePositiveRate is the target probability that DistinctBloom drops
+	// an element whose key was never seen. Defaults to 0.01. The filter
+	// never lets a duplicate through.
+	FalsePositiveRate float64
+
+	// OnDuplicate is called with each element dropped as a duplicate.
+	OnDuplicate ElementHook[T]
+
+	// Now is the clock for DistinctTTL. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// PipeDistinctBy drops elements whose keyFn(v) has already been seen. Unlike
+// PipeDistinct it works for any T and can bound its memory: cfg.Mode picks
+// exact, LRU-capped, time-windowed or Bloom-filter deduplication.
+func PipeDistinctBy[T any, K comparable](p *Pipeline[T], keyFn func(T) K, cfg DistinctConfig[T]) *Pipeline[T] {
+	return &Pipeline[T]{
+		source: &distinctBySource[T, K]{
+			inner: p.source, keyFn: keyFn, set: newKeySet[K](cfg),
+			onDup: cfg.OnDuplicate, hooks: p.hooks,
+		},
+		hooks:   p.hooks,
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+type distinctBySource[T any, K comparable] struct {
+	inner Source[T]
+	keyFn func(T) K
+	set   keySet[K]
+	onDup ElementHook[T]
+	hooks *Hooks[T]
+	err   errSlot
+}
+
+func (s *distinctBySource[T, K]) Next() (T, bool) {
+	for {
+		v, ok := s.inner.Next()
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		var k K
+		if s.hooks.RecoverPanics {
+			var perr *PanicError
+			var abort bool
+			k, perr, abort = callGuarded(s.hooks, s.keyFn, v)
+			if perr != nil {
+				s.err.set(perr)
+				if abort {
+					var zero T
+					return zero, false
+				}
+				continue
+			}
+		} else {
+			k = s.keyFn(v)
+		}
+		if !s.set.seen(k) {
+			return v, true
+		}
+		if s.onDup != nil {
+			s.onDup(v)
+		}
+	}
+}
+
+func (s *distinctBySource[T, K]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *distinctBySource[T, K]) Close() error { return closeSource(s.inner) }
+
+// keySet reports whether k was seen before and records it.
+type keySet[K comparable] interface {
+	seen(k K) bool
+}
+
+func newKeySet[K comparable, T any](cfg DistinctConfig[T]) keySet[K] {
+	n := cfg.MaxKeys
+	if n <= 0 {
+		n = DefaultDistinctKeys
+	}
+	switch cfg.Mode {
+	case DistinctLRU:
+		return &lruKeySet[K]{max: n, order: list.New(), index: make(map[K]*list.Element)}
+	case DistinctTTL:
+		now := cfg.Now
+		if now == nil {
+			now = time.Now
+		}
+		window := cfg.Window
+		if window <= 0 {
+			window = DefaultDistinctWindow
+		}
+		return &ttlKeySet[K]{window: window, now: now, first: make(map[K]time.Time)}
+	case DistinctBloom:
+		fp := cfg.FalsePositiveRate
+		if fp <= 0 || fp >= 1 {
+			fp = 0.01
+		}
+		return newBloomKeySet[K](n, fp)
+	}
+	return exactKeySet[K]{}
+}
+
+type exactKeySet[K comparable] map[K]struct{}
+
+func (s exactKeySet[K]) seen(k K) bool {
+	if _, ok := s[k]; ok {
+		return true
+	}
+	s[k] = struct{}{}
+	return false
+}
+
+// lruKeySet remembers the max most recently seen keys; a repeat sighting
+// refreshes the key.
+type lruKeySet[K comparable] struct {
+	max   int
+	order *list.List // front = most recent
+	index map[K]*list.Element
+}
+
+func (s *lruKeySet[K]) seen(k K) bool {
+	if e, ok := s.index[k]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+	s.index[k] = s.order.PushFront(k)
+	if s.order.Len() > s.max {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.index, oldest.Value.(K))
+	}
+	return false
+}
+
+// ttlKeySet suppresses a key for window after it was first emitted. Expired
+// keys are evicted in emission order, so memory is bounded by the number of
+// distinct keys per window.
+type ttlKeySet[K comparable] struct {
+	window time.Duration
+	now    func() time.Time
+	first  map[K]time.Time
+	queue  []ttlEntry[K]
+}
+
+type ttlEntry[K comparable] struct {
+	key K
+	at  time.Time
+}
+
+func (s *ttlKeySet[K]) seen(k K) bool {
+	now := s.now()
+	s.expire(now)
+	if at, ok := s.first[k]; ok && now.Sub(at) < s.window {
+		return true
+	}
+	s.first[k] = now
+	s.queue = append(s.queue, ttlEntry[K]{key: k, at: now})
+	return false
+}
+
+func (s *ttlKeySet[K]) expire(now time.Time) {
+	i := 0
+	for ; i < len(s.queue) && now.Sub(s.queue[i].at) >= s.window; i++ {
+		e := s.queue[i]
+		// The key may have been re-emitted since; only the latest entry owns it.
+		if s.first[e.key].Equal(e.at) {
+			delete(s.first, e.key)
+		}
+	}
+	if i > 0 {
+		var zero ttlEntry[K]
+		for j := 0; j < i; j++ {
+			s.queue[j] = zero
+		}
+		s.queue = s.queue[i:]
+	}
+}
+
+// bloomKeySet is a Bloom filter over KeyHash: no false negatives, so a
+// duplicate is never emitted, but a new key is dropped with probability ≈ fp
+// once n keys have been added.
+type bloomKeySet[K comparable] struct {
+	bits []uint64
+	m    uint64
+	k    int
+}
+
+func newBloomKeySet[K comparable](n int, fp float64) *bloomKeySet[K] {
+	m := math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2))
+	k := max(int(math.Round(m/float64(n)*math.Ln2)), 1)
+	words := (uint64(m) + 63) / 64
+	return &bloomKeySet[K]{bits: make([]uint64, words), m: words * 64, k: k}
+}
+
+func (s *bloomKeySet[K]) seen(key K) bool {
+	x := KeyHash(key)
+	h1, h2 := x&0xffffffff, x>>32|1
+	present := true
+	for i := 0; i < s.k; i++ {
+		bit := (h1 + uint64(i)*h2) % s.m
+		w, mask := bit/64, uint64(1)<<(bit%64)
+		if s.bits[w]&mask == 0 {
+			present = false
+			s.bits[w] |= mask
+		}
+	}
+	return present
+}
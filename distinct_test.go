@@ -0,0 +1,131 @@
+package gosplice
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type event struct {
+	ID  string
+	Seq int
+}
+
+func eventID(e event) string { return e.ID }
+
+func seqs(es []event) []int {
+	out := make([]int, len(es))
+	for i, e := range es {
+		out[i] = e.Seq
+	}
+	return out
+}
+
+func TestPipeDistinctBy_Exact(t *testing.T) {
+	in := []event{{"a", 0}, {"b", 1}, {"a", 2}, {"c", 3}, {"b", 4}}
+	var dropped []int
+	got := PipeDistinctBy(FromSlice(in), eventID, DistinctConfig[event]{
+		OnDuplicate: func(e event) { dropped = append(dropped, e.Seq) },
+	}).Collect()
+	if !slices.Equal(seqs(got), []int{0, 1, 3}) {
+		t.Errorf("got %v", seqs(got))
+	}
+	if !slices.Equal(dropped, []int{2, 4}) {
+		t.Errorf("dropped %v", dropped)
+	}
+}
+
+func TestPipeDistinctBy_LRU(t *testing.T) {
+	// Capacity 2: "a" is evicted by "b","c" and passes again; "c" is still remembered.
+	in := []event{{"a", 0}, {"b", 1}, {"c", 2}, {"a", 3}, {"c", 4}}
+	got := PipeDistinctBy(FromSlice(in), eventID, DistinctConfig[event]{
+		Mode: DistinctLRU, MaxKeys: 2,
+	}).Collect()
+	if !slices.Equal(seqs(got), []int{0, 1, 2, 3}) {
+		t.Errorf("got %v", seqs(got))
+	}
+
+	// A repeat sighting refreshes the key, so "a" survives the later insert.
+	in = []event{{"a", 0}, {"b", 1}, {"a", 2}, {"c", 3}, {"a", 4}}
+	got = PipeDistinctBy(FromSlice(in), eventID, DistinctConfig[event]{
+		Mode: DistinctLRU, MaxKeys: 2,
+	}).Collect()
+	if !slices.Equal(seqs(got), []int{0, 1, 3}) {
+		t.Errorf("refresh: got %v", seqs(got))
+	}
+}
+
+func TestPipeDistinctBy_TTL(t *testing.T) {
+	clock := time.Unix(0, 0)
+	type tick struct {
+		ID string
+		At time.Duration
+	}
+	in := []tick{{"a", 0}, {"a", 5 * time.Second}, {"b", 6 * time.Second}, {"a", 10 * time.Second}, {"a", 12 * time.Second}, {"b", 20 * time.Second}}
+	src := FromSlice(in).Peek(func(tk tick) { clock = time.Unix(0, 0).Add(tk.At) })
+	got := PipeDistinctBy(src, func(tk tick) string { return tk.ID }, DistinctConfig[tick]{
+		Mode: DistinctTTL, Window: 10 * time.Second, Now: func() time.Time { return clock },
+	}).Collect()
+	want := []tick{{"a", 0}, {"b", 6 * time.Second}, {"a", 10 * time.Second}, {"b", 20 * time.Second}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPipeDistinctBy_TTLEvicts(t *testing.T) {
+	clock := time.Unix(0, 0)
+	set := newKeySet[int](DistinctConfig[int]{Mode: DistinctTTL, Window: time.Second, Now: func() time.Time { return clock }}).(*ttlKeySet[int])
+	for i := 0; i < 1000; i++ {
+		set.seen(i)
+	}
+	clock = clock.Add(2 * time.Second)
+	set.seen(-1)
+	if len(set.first) != 1 || len(set.queue) != 1 {
+		t.Errorf("expired keys kept: map %d, queue %d", len(set.first), len(set.queue))
+	}
+}
+
+func TestPipeDistinctBy_TTLDefaultWindow(t *testing.T) {
+	set := newKeySet[int](DistinctConfig[int]{Mode: DistinctTTL}).(*ttlKeySet[int])
+	if set.window != DefaultDistinctWindow {
+		t.Errorf("window = %v, want %v", set.window, DefaultDistinctWindow)
+	}
+	got := PipeDistinctBy(FromSlice([]int{1, 1, 2, 1}), func(i int) int { return i }, DistinctConfig[int]{Mode: DistinctTTL}).Collect()
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestPipeDistinctBy_Bloom(t *testing.T) {
+	const n = 20_000
+	var dropped int
+	got := PipeDistinctBy(FromRange(0, 2*n), func(i int) int { return i % n }, DistinctConfig[int]{
+		Mode: DistinctBloom, MaxKeys: n, FalsePositiveRate: 0.01,
+		OnDuplicate: func(int) { dropped++ },
+	}).Count()
+	// Every repeat is dropped; at most ~1% of new keys are false positives.
+	if got > n || got < n*97/100 {
+		t.Errorf("emitted %d of %d distinct keys", got, n)
+	}
+	if got+dropped != 2*n {
+		t.Errorf("emitted %d + dropped %d != %d", got, dropped, 2*n)
+	}
+}
+
+func TestPipeDistinctBy_PanicRecovery(t *testing.T) {
+	p := FromSlice([]string{"a", "boom", "b", "a"}).WithPanicRecovery()
+	got := PipeDistinctBy(p, func(s string) string {
+		if s == "boom" {
+			panic(errors.New("bad key"))
+		}
+		return s
+	}, DistinctConfig[string]{})
+	if out := got.Collect(); !slices.Equal(out, []string{"a", "b"}) {
+		t.Errorf("got %v", out)
+	}
+	var pe *PanicError
+	if !errors.As(got.Err(), &pe) {
+		t.Errorf("Err() = %v", got.Err())
+	}
+}
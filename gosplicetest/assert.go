@@ -0,0 +1,108 @@
+package gosplicetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	gs "github.com/lacolle87/gosplice"
+)
+
+// AssertOrder fails the test unless got equals want element by element.
+func AssertOrder[T any](t testing.TB, got, want []T) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("length mismatch: got %d %v, want %d %v", len(got), got, len(want), want)
+		return
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("element %d: got %v, want %v (got %v, want %v)", i, got[i], want[i], got, want)
+			return
+		}
+	}
+}
+
+// AssertSameElements fails the test unless got and want contain the same
+// elements regardless of order. Use for unordered parallel stages.
+func AssertSameElements[T comparable](t testing.TB, got, want []T) {
+	t.Helper()
+	counts := make(map[T]int, len(want))
+	for _, v := range want {
+		counts[v]++
+	}
+	for _, v := range got {
+		counts[v]--
+	}
+	for v, n := range counts {
+		if n != 0 {
+			t.Errorf("element %v: count differs by %d (got %v, want %v)", v, -n, got, want)
+			return
+		}
+	}
+}
+
+// AssertHookOrder fails the test unless the recorded events match want exactly.
+func AssertHookOrder[T any](t testing.TB, h *HookRecorder[T], want ...string) {
+	t.Helper()
+	if got := h.Events(); !slices.Equal(got, want) {
+		t.Errorf("hook order:\n got  %v\n want %v", got, want)
+	}
+}
+
+// AssertHookSubsequence fails the test unless want appears within the
+// recorded events in the same relative order (other events may interleave).
+func AssertHookSubsequence[T any](t testing.TB, h *HookRecorder[T], want ...string) {
+	t.Helper()
+	got := h.Events()
+	j := 0
+	for _, ev := range got {
+		if j < len(want) && ev == want[j] {
+			j++
+		}
+	}
+	if j != len(want) {
+		t.Errorf("hook subsequence %v not found in %v (matched %d)", want, got, j)
+	}
+}
+
+// AssertCanceled fails the test unless p.Err() reports context cancellation
+// or an expired deadline.
+func AssertCanceled[T any](t testing.TB, p *gs.Pipeline[T]) {
+	t.Helper()
+	err := p.Err()
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context cancellation, got %v", err)
+	}
+}
+
+// AssertErr fails the test unless errors.Is(p.Err(), target).
+func AssertErr[T any](t testing.TB, p *gs.Pipeline[T], target error) {
+	t.Helper()
+	if err := p.Err(); !errors.Is(err, target) {
+		t.Errorf("expected error %v, got %v", target, err)
+	}
+}
+
+// AssertNoErr fails the test if p.Err() is non-nil.
+func AssertNoErr[T any](t testing.TB, p *gs.Pipeline[T]) {
+	t.Helper()
+	if err := p.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// AssertPanics fails the test unless fn panics. Returns the recovered value.
+func AssertPanics(t testing.TB, fn func()) (recovered any) {
+	t.Helper()
+	defer func() {
+		recovered = recover()
+		if recovered == nil {
+			t.Errorf("expected panic, got none")
+		}
+	}()
+	fn()
+	return nil
+}
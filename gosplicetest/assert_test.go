@@ -0,0 +1,53 @@
+package gosplicetest
+
+import (
+	"context"
+	"testing"
+
+	gs "github.com/lacolle87/gosplice"
+)
+
+func TestAssertOrderMismatch(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	AssertOrder(ft, []int{1, 2}, []int{2, 1})
+	AssertOrder(ft, []int{1}, []int{1, 2})
+	if len(ft.errs) != 2 {
+		t.Errorf("expected 2 failures, got %v", ft.errs)
+	}
+}
+
+func TestAssertSameElements(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	AssertSameElements(ft, []int{3, 1, 2}, []int{1, 2, 3})
+	if len(ft.errs) != 0 {
+		t.Errorf("unexpected failure: %v", ft.errs)
+	}
+	AssertSameElements(ft, []int{1, 1, 2}, []int{1, 2, 2})
+	if len(ft.errs) != 1 {
+		t.Errorf("expected 1 failure, got %v", ft.errs)
+	}
+}
+
+func TestAssertCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := gs.FromSlice([]int{1, 2, 3}).WithContext(ctx)
+	p.Collect()
+	AssertCanceled(t, p)
+
+	ft := &fakeTB{TB: t}
+	ok := gs.FromSlice([]int{1})
+	ok.Collect()
+	AssertCanceled(ft, ok)
+	if len(ft.errs) != 1 {
+		t.Errorf("expected failure for uncancelled pipeline, got %v", ft.errs)
+	}
+}
+
+func TestAssertPanicsNoPanic(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	AssertPanics(ft, func() {})
+	if len(ft.errs) != 1 {
+		t.Errorf("expected failure, got %v", ft.errs)
+	}
+}
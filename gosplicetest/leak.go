@@ -0,0 +1,109 @@
+package gosplicetest
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// LeakTimeout is how long VerifyNoLeaks waits for background goroutines
+// to exit before reporting them.
+var LeakTimeout = time.Second
+
+const pkgFrame = "github.com/lacolle87/gosplice."
+
+// VerifyNoLeaks snapshots the running goroutines and registers a cleanup
+// that fails the test if goroutines started afterwards are still running
+// gosplice code when the test ends — e.g. PipeMapParallelStream workers
+// and dispatchers, or the pipeBatchWithTimeout reader.
+//
+// Call it first thing in the test:
+//
+//	func TestStream(t *testing.T) {
+//	    gosplicetest.VerifyNoLeaks(t)
+//	    ...
+//	}
+func VerifyNoLeaks(t testing.TB) {
+	t.Helper()
+	before := goroutineIDs()
+	t.Cleanup(func() {
+		t.Helper()
+		var leaked []string
+		deadline := time.Now().Add(LeakTimeout)
+		for {
+			leaked = leakedStacks(before)
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if len(leaked) > 0 {
+			t.Errorf("%d leaked gosplice goroutine(s):\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+	})
+}
+
+// GoroutineCount returns the number of other goroutines currently
+// executing gosplice code.
+func GoroutineCount() int {
+	return len(leakedStacks(nil))
+}
+
+func goroutineIDs() map[int]bool {
+	ids := make(map[int]bool)
+	for _, g := range stacks() {
+		ids[goroutineID(g)] = true
+	}
+	return ids
+}
+
+func leakedStacks(before map[int]bool) []string {
+	var out []string
+	for _, g := range stacks() {
+		if before[goroutineID(g)] || !runsGosplice(g) {
+			continue
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+func runsGosplice(stack string) bool {
+	for _, line := range strings.Split(stack, "\n") {
+		line = strings.TrimPrefix(line, "created by ")
+		if strings.HasPrefix(line, pkgFrame) {
+			return true
+		}
+	}
+	return false
+}
+
+// stacks returns the stack of every goroutine except the caller's.
+func stacks() []string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	all := strings.Split(string(buf), "\n\n")
+	return all[1:]
+}
+
+func goroutineID(stack string) int {
+	rest, ok := strings.CutPrefix(stack, "goroutine ")
+	if !ok {
+		return -1
+	}
+	idStr, _, _ := strings.Cut(rest, " ")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return -1
+	}
+	return id
+}
@@ -0,0 +1,89 @@
+package gosplicetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	gs "github.com/lacolle87/gosplice"
+)
+
+type fakeTB struct {
+	testing.TB
+	cleanups []func()
+	errs     []string
+}
+
+func (f *fakeTB) Helper()                   { f.TB.Helper() }
+func (f *fakeTB) Cleanup(fn func())         { f.cleanups = append(f.cleanups, fn) }
+func (f *fakeTB) Errorf(s string, a ...any) { f.errs = append(f.errs, fmt.Sprintf(s, a...)) }
+
+func (f *fakeTB) runCleanups() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestVerifyNoLeaksClean(t *testing.T) {
+	VerifyNoLeaks(t)
+	got := gs.PipeMapParallelStream(gs.FromSource[int](NewSource(1, 2, 3, 4)), 2, 2,
+		func(n int) int { return n * 2 }).Collect()
+	AssertOrder(t, got, []int{2, 4, 6, 8})
+}
+
+func TestVerifyNoLeaksBatchTimeout(t *testing.T) {
+	VerifyNoLeaks(t)
+	got := gs.PipeBatch(gs.FromSource[int](NewSource(1, 2, 3)),
+		gs.BatchConfig{Size: 2, MaxWait: 10 * time.Millisecond}).Collect()
+	AssertOrder(t, got, [][]int{{1, 2}, {3}})
+}
+
+func TestVerifyNoLeaksDetectsLeak(t *testing.T) {
+	old := LeakTimeout
+	LeakTimeout = 50 * time.Millisecond
+	defer func() { LeakTimeout = old }()
+
+	ft := &fakeTB{TB: t}
+	VerifyNoLeaks(ft)
+
+	// Abandoning the pipeline without Close leaves the batching goroutine
+	// and its reader parked on a source that never yields.
+	block := make(chan int)
+	defer close(block)
+	p := gs.PipeBatch(gs.FromChannel(block), gs.BatchConfig{Size: 10, MaxWait: time.Millisecond})
+	_ = p
+
+	ft.runCleanups()
+	if len(ft.errs) != 1 || !strings.Contains(ft.errs[0], "pipeBatchWithTimeout") {
+		t.Fatalf("expected leak report mentioning pipeBatchWithTimeout, got %v", ft.errs)
+	}
+}
+
+func TestVerifyNoLeaksAfterClose(t *testing.T) {
+	VerifyNoLeaks(t)
+	src := NewSource(1, 2, 3, 4, 5, 6, 7, 8).DelayEach(5 * time.Millisecond)
+	p := gs.PipeMapParallelStream(gs.FromSource[int](src), 2, 1, func(n int) int { return n })
+	p.Take(2).Collect()
+	if !src.Closed() {
+		t.Error("expected Take to close the scripted source")
+	}
+}
+
+func TestVerifyNoLeaksTimedTake(t *testing.T) {
+	VerifyNoLeaks(t)
+	src := NewSource(1, 2, 3, 4, 5, 6, 7, 8).DelayEach(10 * time.Millisecond)
+	got := gs.FromSource[int](src).Audit(time.Millisecond).Take(2).Collect()
+	if len(got) != 2 {
+		t.Errorf("got %v", got)
+	}
+	if !src.Closed() {
+		t.Error("expected Take to close the scripted source")
+	}
+}
+
+func TestGoroutineCount(t *testing.T) {
+	if n := GoroutineCount(); n < 0 {
+		t.Errorf("unexpected count %d", n)
+	}
+}
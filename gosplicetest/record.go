@@ -0,0 +1,119 @@
+package gosplicetest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	gs "github.com/lacolle87/gosplice"
+)
+
+// Recorder is a sink that records every value it receives.
+// Safe for concurrent use.
+type Recorder[T any] struct {
+	mu     sync.Mutex
+	values []T
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder[T any]() *Recorder[T] {
+	return &Recorder[T]{}
+}
+
+// Record appends v. Pass it to ForEach: p.ForEach(rec.Record).
+func (r *Recorder[T]) Record(v T) {
+	r.mu.Lock()
+	r.values = append(r.values, v)
+	r.mu.Unlock()
+}
+
+// Hook returns an ElementHook that records every element it sees.
+func (r *Recorder[T]) Hook() gs.ElementHook[T] {
+	return r.Record
+}
+
+// Values returns a copy of the recorded values in arrival order.
+func (r *Recorder[T]) Values() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]T, len(r.values))
+	copy(out, r.values)
+	return out
+}
+
+// Len returns the number of recorded values.
+func (r *Recorder[T]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.values)
+}
+
+// Reset discards all recorded values.
+func (r *Recorder[T]) Reset() {
+	r.mu.Lock()
+	r.values = nil
+	r.mu.Unlock()
+}
+
+// Hook event names recorded by HookRecorder.
+const (
+	EventElement    = "element"
+	EventError      = "error"
+	EventBatch      = "batch"
+	EventCompletion = "completion"
+	EventTimeout    = "timeout"
+)
+
+// HookRecorder records the order in which pipeline hooks fire.
+// Each event is a short string: "element:<v>", "error:<err>", "batch:<n>",
+// "completion" or "timeout". Safe for concurrent use.
+type HookRecorder[T any] struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// NewHookRecorder returns an empty HookRecorder.
+func NewHookRecorder[T any]() *HookRecorder[T] {
+	return &HookRecorder[T]{}
+}
+
+// Attach registers element, error, batch, completion and timeout hooks on p
+// and returns p for chaining. It does not set an ErrorHandler, so error
+// hooks fire with the default Skip behaviour.
+func (h *HookRecorder[T]) Attach(p *gs.Pipeline[T]) *gs.Pipeline[T] {
+	return p.
+		WithElementHook(func(v T) { h.add(fmt.Sprintf("%s:%v", EventElement, v)) }).
+		WithErrorHook(func(err error, _ T) { h.add(fmt.Sprintf("%s:%v", EventError, err)) }).
+		WithBatchHook(func(b []T) { h.add(fmt.Sprintf("%s:%d", EventBatch, len(b))) }).
+		WithCompletionHook(func() { h.add(EventCompletion) }).
+		WithTimeoutHook(func(time.Duration) { h.add(EventTimeout) })
+}
+
+func (h *HookRecorder[T]) add(ev string) {
+	h.mu.Lock()
+	h.events = append(h.events, ev)
+	h.mu.Unlock()
+}
+
+// Events returns a copy of the recorded events in firing order.
+func (h *HookRecorder[T]) Events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]string, len(h.events))
+	copy(out, h.events)
+	return out
+}
+
+// Count returns how many events of the given kind (e.g. EventElement) fired.
+func (h *HookRecorder[T]) Count(kind string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, ev := range h.events {
+		if k, _, _ := strings.Cut(ev, ":"); k == kind {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+package gosplicetest
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	gs "github.com/lacolle87/gosplice"
+)
+
+func TestRecorderForEach(t *testing.T) {
+	rec := NewRecorder[int]()
+	gs.FromSlice([]int{3, 1, 2}).ForEach(rec.Record)
+	AssertOrder(t, rec.Values(), []int{3, 1, 2})
+	rec.Reset()
+	if rec.Len() != 0 {
+		t.Errorf("expected empty after reset, got %d", rec.Len())
+	}
+}
+
+func TestRecorderHook(t *testing.T) {
+	rec := NewRecorder[int]()
+	gs.FromSlice([]int{1, 2}).WithElementHook(rec.Hook()).Collect()
+	AssertOrder(t, rec.Values(), []int{1, 2})
+}
+
+func TestHookRecorderOrder(t *testing.T) {
+	h := NewHookRecorder[string]()
+	p := h.Attach(gs.FromSlice([]string{"1", "x", "3"}))
+	got := gs.PipeMapErr(p, strconv.Atoi).Collect()
+	AssertOrder(t, got, []int{1, 3})
+	AssertHookOrder(t, h,
+		"element:1",
+		"element:x",
+		`error:strconv.Atoi: parsing "x": invalid syntax`,
+		"element:3",
+	)
+	if h.Count(EventError) != 1 || h.Count(EventElement) != 3 {
+		t.Errorf("unexpected counts in %v", h.Events())
+	}
+}
+
+func TestHookRecorderBatchAndCompletion(t *testing.T) {
+	h := NewHookRecorder[int]()
+	p := h.Attach(gs.FromSlice([]int{1, 2, 3}))
+	gs.PipeBatch(p, gs.BatchConfig{Size: 2}).Collect()
+	h.Attach(gs.FromSlice([]int{9})).Collect()
+	AssertHookSubsequence(t, h, "element:1", "element:2", "batch:2", "element:3", "batch:1", "element:9", EventCompletion)
+}
+
+func TestHookRecorderTimeout(t *testing.T) {
+	h := NewHookRecorder[int]()
+	p := h.Attach(gs.FromSource[int](NewSource(1).ErrorAt(0, errors.New("io"))).WithTimeout(time.Second))
+	p.Collect()
+	AssertHookOrder(t, h, EventTimeout, EventCompletion)
+}
@@ -0,0 +1,149 @@
+// Package gosplicetest provides helpers for testing code built on gosplice
+// pipelines: a scripted Source that injects delays, errors and panics at
+// given positions, recording sinks and hooks, and assertions for element
+// order, hook firing order, cancellation and goroutine leaks.
+package gosplicetest
+
+import (
+	"sync"
+	"time"
+)
+
+// Source is a scripted gosplice.Source over a fixed slice of values.
+// Delays, errors and panics can be injected at given positions (0-based
+// index of the element about to be emitted).
+//
+// Source is safe for concurrent use, so it can feed parallel stages that
+// read it from a background goroutine.
+type Source[T any] struct {
+	mu     sync.Mutex
+	values []T
+	pos    int
+	calls  int
+	delays map[int]time.Duration
+	errs   map[int]error
+	panics map[int]any
+	err    error
+	done   bool
+	closed bool
+}
+
+// NewSource returns a scripted source that yields values in order.
+func NewSource[T any](values ...T) *Source[T] {
+	return &Source[T]{
+		values: values,
+		delays: make(map[int]time.Duration),
+		errs:   make(map[int]error),
+		panics: make(map[int]any),
+	}
+}
+
+// DelayAt sleeps for d before emitting the element at position i.
+func (s *Source[T]) DelayAt(i int, d time.Duration) *Source[T] {
+	s.delays[i] = d
+	return s
+}
+
+// DelayEach sleeps for d before emitting every element.
+func (s *Source[T]) DelayEach(d time.Duration) *Source[T] {
+	for i := range s.values {
+		s.delays[i] = d
+	}
+	return s
+}
+
+// ErrorAt stops the source at position i and reports err from Err(),
+// the same way a failing reader ends a FromReader pipeline.
+func (s *Source[T]) ErrorAt(i int, err error) *Source[T] {
+	s.errs[i] = err
+	return s
+}
+
+// PanicAt panics with v when the element at position i is requested.
+func (s *Source[T]) PanicAt(i int, v any) *Source[T] {
+	s.panics[i] = v
+	return s
+}
+
+func (s *Source[T]) Next() (T, bool) {
+	s.mu.Lock()
+	s.calls++
+	var zero T
+	if s.done || s.pos >= len(s.values) {
+		s.done = true
+		s.mu.Unlock()
+		return zero, false
+	}
+	i := s.pos
+	d := s.delays[i]
+	s.mu.Unlock()
+
+	if d > 0 {
+		time.Sleep(d)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return zero, false
+	}
+	if pv, ok := s.panics[i]; ok {
+		delete(s.panics, i)
+		panic(pv)
+	}
+	if err, ok := s.errs[i]; ok {
+		s.err = err
+		s.done = true
+		return zero, false
+	}
+	v := s.values[i]
+	s.pos++
+	return v, true
+}
+
+// Err returns the error injected with ErrorAt once it has been reached.
+// Wrapping the source with gosplice.FromSource surfaces it via Pipeline.Err.
+func (s *Source[T]) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Emitted returns the number of elements handed out so far.
+func (s *Source[T]) Emitted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pos
+}
+
+// Calls returns the number of times Next has been called.
+func (s *Source[T]) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// Exhausted reports whether the source has returned (zero, false).
+func (s *Source[T]) Exhausted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// Close marks the source closed; further Next calls return (zero, false).
+// Stages propagate Pipeline.Close (and early exits in Take, First, Any)
+// down to the source, so Closed lets tests assert that cleanup happened.
+func (s *Source[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.done = true
+	return nil
+}
+
+// Closed reports whether Close has been called.
+func (s *Source[T]) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
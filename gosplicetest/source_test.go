@@ -0,0 +1,65 @@
+package gosplicetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	gs "github.com/lacolle87/gosplice"
+)
+
+func TestSourceYieldsInOrder(t *testing.T) {
+	src := NewSource(1, 2, 3)
+	p := gs.FromSource[int](src)
+	AssertOrder(t, p.Collect(), []int{1, 2, 3})
+	AssertNoErr(t, p)
+	if !src.Exhausted() || src.Emitted() != 3 {
+		t.Errorf("expected exhausted after 3, got exhausted=%v emitted=%d", src.Exhausted(), src.Emitted())
+	}
+}
+
+func TestSourceErrorAt(t *testing.T) {
+	boom := errors.New("boom")
+	src := NewSource(1, 2, 3, 4).ErrorAt(2, boom)
+	p := gs.FromSource[int](src)
+	AssertOrder(t, p.Collect(), []int{1, 2})
+	AssertErr(t, p, boom)
+}
+
+func TestSourcePanicAt(t *testing.T) {
+	src := NewSource("a", "b").PanicAt(1, "kaboom")
+	v := AssertPanics(t, func() { gs.FromSource[string](src).Collect() })
+	if v != "kaboom" {
+		t.Errorf("expected kaboom, got %v", v)
+	}
+	if src.Emitted() != 1 {
+		t.Errorf("expected 1 emitted before panic, got %d", src.Emitted())
+	}
+}
+
+func TestSourceDelayAt(t *testing.T) {
+	src := NewSource(1, 2).DelayAt(1, 30*time.Millisecond)
+	start := time.Now()
+	gs.FromSource[int](src).Collect()
+	if el := time.Since(start); el < 25*time.Millisecond {
+		t.Errorf("expected delay, took %v", el)
+	}
+}
+
+func TestSourceTimeoutCancels(t *testing.T) {
+	src := NewSource(1, 2, 3, 4, 5).DelayEach(20 * time.Millisecond)
+	p := gs.FromSource[int](src).WithTimeout(50 * time.Millisecond)
+	got := p.Collect()
+	if len(got) >= 5 {
+		t.Errorf("expected early stop, got %v", got)
+	}
+	AssertCanceled(t, p)
+}
+
+func TestSourceCalls(t *testing.T) {
+	src := NewSource(1, 2, 3)
+	gs.FromSource[int](src).Take(1).Collect()
+	if src.Calls() != 1 {
+		t.Errorf("expected 1 call, got %d", src.Calls())
+	}
+}
@@ -0,0 +1,150 @@
+package gosplice
+
+import "math"
+
+// SummaryAcc accumulates count, sum, mean, variance, min and max of a
+// stream of values in O(1) memory (a running sum and Welford's algorithm).
+// The zero value is ready to use. Partial accumulators from parallel
+// workers combine with Merge.
+type SummaryAcc struct {
+	Total   MeanAcc     `json:"total"`
+	Moments VarianceAcc `json:"moments"`
+	Range   MinMaxAcc   `json:"range"`
+}
+
+func (a *SummaryAcc) Add(x float64) {
+	a.Total.Add(x)
+	a.Moments.Add(x)
+	a.Range.Add(x)
+}
+
+func (a *SummaryAcc) Merge(other *SummaryAcc) {
+	a.Total.Merge(&other.Total)
+	a.Moments.Merge(&other.Moments)
+	a.Range.Merge(&other.Range)
+}
+
+func (a *SummaryAcc) Count() int      { return a.Moments.Count }
+func (a *SummaryAcc) Mean() float64   { return a.Moments.Mean }
+func (a *SummaryAcc) Sum() float64    { return a.Total.Sum }
+func (a *SummaryAcc) StdDev() float64 { return a.Moments.StdDev() }
+
+// Min returns the smallest value other than NaN; NaN if every value was NaN.
+func (a *SummaryAcc) Min() float64 { return a.extreme(a.Range.Min) }
+
+// Max returns the largest value other than NaN; NaN if every value was NaN.
+func (a *SummaryAcc) Max() float64 { return a.extreme(a.Range.Max) }
+
+func (a *SummaryAcc) extreme(x float64) float64 {
+	if a.Range.Count == 0 && a.Count() > 0 {
+		return math.NaN()
+	}
+	return x
+}
+
+// Variance returns the population variance, like VarianceBy.
+func (a *SummaryAcc) Variance() float64 { return a.Moments.Result() }
+
+// StatsAcc is SummaryAcc plus a QuantileSketch for Q1, median and Q3, so it
+// can produce a full Stats without keeping the values. Quartiles are exact
+// for small groups and approximate beyond or after Merge (see
+// QuantileSketch); the sketch cannot rank NaN, so they are NaN once a NaN
+// was added. The zero value is ready to use.
+type StatsAcc struct {
+	SummaryAcc
+	Quartiles *QuantileSketch `json:"quartiles"`
+}
+
+func (a *StatsAcc) Add(x float64) {
+	a.SummaryAcc.Add(x)
+	if a.Quartiles == nil {
+		a.Quartiles = NewQuantileSketch(DefaultCompression)
+	}
+	a.Quartiles.Add(x)
+}
+
+func (a *StatsAcc) Merge(other *StatsAcc) {
+	a.SummaryAcc.Merge(&other.SummaryAcc)
+	if other.Quartiles == nil {
+		return
+	}
+	if a.Quartiles == nil {
+		a.Quartiles = NewQuantileSketch(DefaultCompression)
+	}
+	a.Quartiles.Merge(other.Quartiles)
+}
+
+// Result returns the summary in the same shape as DescribeBy.
+func (a *StatsAcc) Result() Stats {
+	if a.Count() == 0 {
+		return Stats{}
+	}
+	st := Stats{
+		Count:  a.Count(),
+		Sum:    a.Sum(),
+		Mean:   a.Mean(),
+		StdDev: a.StdDev(),
+		Min:    a.Min(),
+		Max:    a.Max(),
+	}
+	if a.Quartiles == nil || a.Quartiles.Count() < a.Count() {
+		// Some values were NaN, which the sketch leaves out.
+		st.Q1, st.Median, st.Q3 = math.NaN(), math.NaN(), math.NaN()
+		return st
+	}
+	st.Q1 = a.Quartiles.Quantile(0.25)
+	st.Median = a.Quartiles.Quantile(0.5)
+	st.Q3 = a.Quartiles.Quantile(0.75)
+	return st
+}
+
+// AggregateByKey computes count, mean, variance, min and max of valueFn(v)
+// per key in a single pass, keeping one SummaryAcc per key instead of the
+// group slices GroupBy would build. Merge maps from parallel workers with
+// MergeByKey.
+func AggregateByKey[T any, K comparable](p *Pipeline[T], keyFn func(T) K, valueFn func(T) float64) map[K]*SummaryAcc {
+	defer p.finalize()
+	return fold(p, make(map[K]*SummaryAcc), func(m map[K]*SummaryAcc, v T) map[K]*SummaryAcc {
+		k := keyFn(v)
+		a := m[k]
+		if a == nil {
+			a = new(SummaryAcc)
+			m[k] = a
+		}
+		a.Add(valueFn(v))
+		return m
+	})
+}
+
+// DescribeByKey is DescribeBy per key ("describe price by city") in a single
+// pass, with one StatsAcc per key.
+func DescribeByKey[T any, K comparable](p *Pipeline[T], keyFn func(T) K, valueFn func(T) float64) map[K]Stats {
+	defer p.finalize()
+	accs := fold(p, make(map[K]*StatsAcc), func(m map[K]*StatsAcc, v T) map[K]*StatsAcc {
+		k := keyFn(v)
+		a := m[k]
+		if a == nil {
+			a = new(StatsAcc)
+			m[k] = a
+		}
+		a.Add(valueFn(v))
+		return m
+	})
+	out := make(map[K]Stats, len(accs))
+	for k, a := range accs {
+		out[k] = a.Result()
+	}
+	return out
+}
+
+// MergeByKey merges per-key accumulators from src into dst, adding keys
+// dst does not have yet (src's accumulators are then shared, not copied).
+func MergeByKey[K comparable, A interface{ Merge(A) }](dst, src map[K]A) {
+	for k, a := range src {
+		if d, ok := dst[k]; ok {
+			d.Merge(a)
+		} else {
+			dst[k] = a
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package gosplice
+
+import (
+	"math"
+	"testing"
+)
+
+type listing struct {
+	City  string
+	Price float64
+}
+
+var listings = []listing{
+	{"Paris", 500}, {"Lyon", 200}, {"Paris", 700}, {"Lyon", 300},
+	{"Paris", 600}, {"Nice", 400}, {"Lyon", 250},
+}
+
+func listingCity(l listing) string   { return l.City }
+func listingPrice(l listing) float64 { return l.Price }
+
+func TestDescribeByKey_MatchesDescribeBy(t *testing.T) {
+	got := DescribeByKey(FromSlice(listings), listingCity, listingPrice)
+	if len(got) != 3 {
+		t.Fatalf("got %d groups", len(got))
+	}
+	for city, s := range got {
+		want := DescribeBy(FromSlice(listings).Filter(func(l listing) bool { return l.City == city }), listingPrice)
+		if s.Count != want.Count {
+			t.Errorf("%s count: got %d, want %d", city, s.Count, want.Count)
+		}
+		pairs := [][2]float64{
+			{s.Sum, want.Sum}, {s.Mean, want.Mean}, {s.StdDev, want.StdDev}, {s.Min, want.Min},
+			{s.Q1, want.Q1}, {s.Median, want.Median}, {s.Q3, want.Q3}, {s.Max, want.Max},
+		}
+		for i, p := range pairs {
+			if !approxEqual(p[0], p[1], eps) {
+				t.Errorf("%s field %d: got %v, want %v", city, i, p[0], p[1])
+			}
+		}
+	}
+}
+
+func TestAggregateByKey(t *testing.T) {
+	got := AggregateByKey(FromSlice(listings), listingCity, listingPrice)
+	lyon := got["Lyon"]
+	if lyon.Count() != 3 || lyon.Min() != 200 || lyon.Max() != 300 || lyon.Sum() != 750 {
+		t.Errorf("Lyon = count %d min %v max %v sum %v", lyon.Count(), lyon.Min(), lyon.Max(), lyon.Sum())
+	}
+	if !approxEqual(lyon.Variance(), Variance([]float64{200, 300, 250}), eps) {
+		t.Errorf("Lyon variance = %v", lyon.Variance())
+	}
+	if nice := got["Nice"]; nice.Variance() != 0 || nice.Mean() != 400 {
+		t.Errorf("Nice = %+v", *nice)
+	}
+}
+
+func TestSummaryAcc_MergeMatchesSinglePass(t *testing.T) {
+	var whole, left, right SummaryAcc
+	for i := 0; i < 1000; i++ {
+		x := math.Sin(float64(i)) * 100
+		whole.Add(x)
+		if i < 300 {
+			left.Add(x)
+		} else {
+			right.Add(x)
+		}
+	}
+	var empty SummaryAcc
+	left.Merge(&empty)
+	left.Merge(&right)
+	if left.Count() != whole.Count() || left.Min() != whole.Min() || left.Max() != whole.Max() {
+		t.Fatalf("merged %+v, whole %+v", left, whole)
+	}
+	if !approxEqual(left.Mean(), whole.Mean(), 1e-9) || !approxEqual(left.Variance(), whole.Variance(), 1e-6) {
+		t.Errorf("mean %v/%v variance %v/%v", left.Mean(), whole.Mean(), left.Variance(), whole.Variance())
+	}
+
+	var fromEmpty SummaryAcc
+	fromEmpty.Merge(&whole)
+	if fromEmpty != whole {
+		t.Error("merging into an empty accumulator should copy")
+	}
+}
+
+func TestMergeByKey_Parallel(t *testing.T) {
+	half := len(listings) / 2
+	a := DescribeByKey(FromSlice(listings), listingCity, listingPrice)
+
+	dst := AggregateByKey(FromSlice(listings[:half]), listingCity, listingPrice)
+	MergeByKey(dst, AggregateByKey(FromSlice(listings[half:]), listingCity, listingPrice))
+	for city, s := range a {
+		if dst[city].Count() != s.Count || !approxEqual(dst[city].Mean(), s.Mean, eps) ||
+			!approxEqual(dst[city].StdDev(), s.StdDev, eps) {
+			t.Errorf("%s: merged %+v, want %+v", city, *dst[city], s)
+		}
+	}
+
+	var p1, p2 StatsAcc
+	p1.Add(500)
+	p2.Add(700)
+	p2.Add(600)
+	p1.Merge(&p2)
+	if got := p1.Result(); got != a["Paris"] {
+		t.Errorf("StatsAcc merge = %+v, want %+v", got, a["Paris"])
+	}
+}
@@ -25,6 +25,10 @@ type ErrorHandler[T any] func(err error, elem T, attempt int) ErrorAction
 // BatchHook is called when PipeBatch emits a complete batch.
 type BatchHook[T any] func([]T)
 
+// ShedHook is called for each element a streaming stage drops under load
+// (ShedDropNewest, ShedDropOldest).
+type ShedHook[T any] func(T)
+
 // CompletionHook is called exactly once during pipeline finalization,
 // after the terminal operation completes or the pipeline is cancelled.
 type CompletionHook func()
@@ -37,17 +41,31 @@ type Hooks[T any] struct {
 	OnElement    []ElementHook[T]
 	OnError      []ErrorHook[T]
 	OnBatch      []BatchHook[T]
+	OnShed       []ShedHook[T]
 	OnCompletion []CompletionHook
 	OnTimeout    []TimeoutHook
 	ErrHandler   ErrorHandler[T]
 	Timeout      time.Duration
 	MaxRetries   int
+	// ElementTimeout bounds each call of the ctx stages (PipeMapCtx...);
+	// set by WithElementTimeout.
+	ElementTimeout time.Duration
+	// RecoverPanics is set by WithPanicRecovery and carried to downstream stages.
+	RecoverPanics bool
 }
 
 func newHooks[T any]() *Hooks[T] {
 	return &Hooks[T]{MaxRetries: 3}
 }
 
+// inheritHooks creates hooks for a type-changing stage, carrying over
+// settings that apply to the whole pipeline rather than one element type.
+func inheritHooks[U any, T any](h *Hooks[T]) *Hooks[U] {
+	nh := newHooks[U]()
+	nh.RecoverPanics = h.RecoverPanics
+	return nh
+}
+
 func (h *Hooks[T]) hasElement() bool { return len(h.OnElement) > 0 }
 func (h *Hooks[T]) hasError() bool   { return len(h.OnError) > 0 || h.ErrHandler != nil }
 
@@ -73,6 +91,12 @@ func (h *Hooks[T]) fireBatch(batch []T) {
 	}
 }
 
+func (h *Hooks[T]) fireShed(v T) {
+	for _, hook := range h.OnShed {
+		hook(v)
+	}
+}
+
 func (h *Hooks[T]) fireCompletion() {
 	for _, hook := range h.OnCompletion {
 		hook()
@@ -0,0 +1,152 @@
+package gosplice
+
+import (
+	"context"
+	"time"
+)
+
+// KeyRateConfig controls RateLimitByKey. The embedded RateLimitConfig is
+// the budget of each key.
+type KeyRateConfig[T any] struct {
+	RateLimitConfig
+
+	// Drop skips elements whose key has no token left instead of waiting
+	// for one. Waiting blocks the whole stream, so in wait mode a noisy key
+	// still delays the others; dropping isolates them.
+	Drop bool
+
+	// OnDrop is called for every element dropped in Drop mode.
+	OnDrop ElementHook[T]
+
+	// IdleTimeout evicts a key's bucket after this long without elements.
+	// Defaults to the time an empty bucket takes to refill completely, so
+	// eviction is invisible: a returning key gets the same full bucket it
+	// would have had.
+	IdleTimeout time.Duration
+}
+
+func (c KeyRateConfig[T]) idleTimeout() time.Duration {
+	if c.IdleTimeout > 0 {
+		return c.IdleTimeout
+	}
+	if c.Rate <= 0 {
+		return c.interval()
+	}
+	refill := time.Duration(float64(c.interval()) * float64(c.burst()) / float64(c.Rate))
+	return max(refill, c.interval())
+}
+
+// RateLimitByKey throttles each key separately (per tenant, per host...):
+// every key gets its own token bucket of cfg.Rate per cfg.Interval, so one
+// busy key cannot use up the others' budget. Buckets of idle keys are
+// evicted, bounding memory by the number of recently active keys.
+//
+//	perTenant := gs.RateLimitByKey(requests,
+//	    func(r Request) string { return r.TenantID },
+//	    gs.KeyRateConfig[Request]{
+//	        RateLimitConfig: gs.RateLimitConfig{Rate: 10, Burst: 20},
+//	        Drop:            true,
+//	        OnDrop:          func(r Request) { throttled.Add(1) },
+//	    })
+func RateLimitByKey[T any, K comparable](p *Pipeline[T], keyFn func(T) K, cfg KeyRateConfig[T]) *Pipeline[T] {
+	return &Pipeline[T]{
+		source: &keyLimitSource[T, K]{
+			inner:   p.source,
+			keyFn:   keyFn,
+			cfg:     cfg,
+			idle:    cfg.idleTimeout(),
+			buckets: make(map[K]*tokenBucket),
+			ctx:     p.ctx,
+			hooks:   p.hooks,
+		},
+		hooks:   p.hooks,
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+type keyLimitSource[T any, K comparable] struct {
+	inner     Source[T]
+	keyFn     func(T) K
+	cfg       KeyRateConfig[T]
+	idle      time.Duration
+	buckets   map[K]*tokenBucket
+	lastSweep time.Time
+	ctx       context.Context
+	hooks     *Hooks[T]
+	err       errSlot
+}
+
+func (s *keyLimitSource[T, K]) Next() (T, bool) {
+	var zero T
+	for {
+		v, ok := s.inner.Next()
+		if !ok {
+			return zero, false
+		}
+		var k K
+		if s.hooks.RecoverPanics {
+			var perr *PanicError
+			var abort bool
+			k, perr, abort = callGuarded(s.hooks, s.keyFn, v)
+			if perr != nil {
+				s.err.set(perr)
+				if abort {
+					return zero, false
+				}
+				continue
+			}
+		} else {
+			k = s.keyFn(v)
+		}
+
+		b := s.bucket(k)
+		if s.cfg.Drop {
+			if b.allow() {
+				return v, true
+			}
+			if s.cfg.OnDrop != nil {
+				s.cfg.OnDrop(v)
+			}
+			continue
+		}
+		if !b.waitCtx(s.ctx) {
+			return zero, false
+		}
+		return v, true
+	}
+}
+
+// bucket returns k's bucket, creating it full, and evicts idle buckets at
+// most once per idle timeout so the sweep is amortised O(1) per element.
+func (s *keyLimitSource[T, K]) bucket(k K) *tokenBucket {
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= s.idle {
+		s.lastSweep = now
+		for key, b := range s.buckets {
+			b.mu.Lock()
+			idle := now.Sub(b.lastTime)
+			b.mu.Unlock()
+			if idle >= s.idle {
+				delete(s.buckets, key)
+			}
+		}
+	}
+	b := s.buckets[k]
+	if b == nil {
+		b = newTokenBucket(s.cfg.RateLimitConfig)
+		s.buckets[k] = b
+	}
+	return b
+}
+
+func (s *keyLimitSource[T, K]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *keyLimitSource[T, K]) Close() error { return closeSource(s.inner) }
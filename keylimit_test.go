@@ -0,0 +1,105 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type req struct {
+	Tenant string
+	ID     int
+}
+
+func tenant(r req) string { return r.Tenant }
+
+func TestRateLimitByKey_DropIsolatesKeys(t *testing.T) {
+	// "a" floods; "b" still gets its own budget.
+	in := []req{{"a", 1}, {"a", 2}, {"a", 3}, {"b", 4}, {"a", 5}, {"b", 6}, {"b", 7}}
+	var dropped []int
+	out := RateLimitByKey(FromSlice(in), tenant, KeyRateConfig[req]{
+		RateLimitConfig: RateLimitConfig{Rate: 1, Interval: time.Hour, Burst: 2},
+		Drop:            true,
+		OnDrop:          func(r req) { dropped = append(dropped, r.ID) },
+	}).Collect()
+
+	var ids []int
+	for _, r := range out {
+		ids = append(ids, r.ID)
+	}
+	if !slices.Equal(ids, []int{1, 2, 4, 6}) || !slices.Equal(dropped, []int{3, 5, 7}) {
+		t.Errorf("passed %v, dropped %v", ids, dropped)
+	}
+}
+
+func TestRateLimitByKey_WaitPerKey(t *testing.T) {
+	// 3 keys × 3 elements at 20/s with burst 1: each key waits ~100ms for
+	// its last two, but the keys do not share a budget.
+	var in []req
+	for i := 0; i < 9; i++ {
+		in = append(in, req{string(rune('a' + i%3)), i})
+	}
+	start := time.Now()
+	out := RateLimitByKey(FromSlice(in), tenant, KeyRateConfig[req]{
+		RateLimitConfig: RateLimitConfig{Rate: 20, Burst: 1},
+	}).Collect()
+	elapsed := time.Since(start)
+	if len(out) != 9 {
+		t.Fatalf("got %d", len(out))
+	}
+	// A shared bucket would need ~400ms for 9 elements.
+	if elapsed < 60*time.Millisecond || elapsed > 350*time.Millisecond {
+		t.Errorf("elapsed %v", elapsed)
+	}
+}
+
+func TestRateLimitByKey_EvictsIdleKeys(t *testing.T) {
+	src := &keyLimitSource[req, string]{
+		inner:   FromSlice([]req{}).source,
+		cfg:     KeyRateConfig[req]{RateLimitConfig: RateLimitConfig{Rate: 1000}},
+		idle:    time.Millisecond,
+		buckets: make(map[string]*tokenBucket),
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		src.bucket(k)
+	}
+	time.Sleep(5 * time.Millisecond)
+	src.bucket("d")
+	if len(src.buckets) != 1 {
+		t.Errorf("%d buckets left", len(src.buckets))
+	}
+
+	// Default: evict after a full refill, at least one Interval.
+	cfg := KeyRateConfig[req]{RateLimitConfig: RateLimitConfig{Rate: 10, Burst: 50}}
+	if d := cfg.idleTimeout(); d != 5*time.Second {
+		t.Errorf("default idle timeout %v", d)
+	}
+}
+
+func TestRateLimitByKey_CancelAndPanic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	in := []req{{"a", 1}, {"a", 2}, {"a", 3}}
+	out := RateLimitByKey(FromSlice(in).WithContext(ctx), tenant, KeyRateConfig[req]{
+		RateLimitConfig: RateLimitConfig{Rate: 1, Interval: time.Hour},
+	}).Collect()
+	if len(out) != 1 {
+		t.Errorf("got %v", out)
+	}
+
+	p := RateLimitByKey(FromSlice(in).WithPanicRecovery(), func(r req) string {
+		if r.ID == 2 {
+			panic("bad key")
+		}
+		return r.Tenant
+	}, KeyRateConfig[req]{RateLimitConfig: RateLimitConfig{Rate: 1000}})
+	if got := p.Collect(); len(got) != 2 {
+		t.Errorf("got %v", got)
+	}
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
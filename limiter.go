@@ -0,0 +1,319 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Limiter
+// ---------------------------------------------------------------------------
+
+// Limiter admits weighted units of work. RateLimiter (token bucket),
+// WindowLimiter (sliding-window log) and MultiLimiter (all tiers at once)
+// implement it, so they can be stacked and shared freely.
+type Limiter interface {
+	// ReserveN takes n units now and returns how long to wait before
+	// using them, plus a function that gives them back if the caller
+	// gives up. Reservations are served in order. A negative n counts as
+	// 0, and a NaN n reserves nothing.
+	ReserveN(n float64) (time.Duration, func())
+
+	// WaitN blocks until n units are available. On cancellation the units
+	// are given back and ctx.Err() is returned. A nil ctx waits
+	// indefinitely. A NaN n is rejected with an error.
+	WaitN(ctx context.Context, n float64) error
+}
+
+var errNaNCost = errors.New("gosplice: limiter cost is NaN")
+
+// clampCost maps a negative or NaN cost to 0, so a reservation can neither
+// add units beyond the limit nor poison the limiter's state.
+func clampCost(n float64) float64 {
+	if !(n > 0) {
+		return 0
+	}
+	return n
+}
+
+// waitReserved implements WaitN on top of ReserveN.
+func waitReserved(ctx context.Context, l Limiter, n float64) error {
+	if math.IsNaN(n) {
+		return errNaNCost
+	}
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	d, cancel := l.ReserveN(n)
+	if d <= 0 {
+		return nil
+	}
+	if ctx == nil || ctx.Done() == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ---------------------------------------------------------------------------
+// MultiLimiter — layered quotas
+// ---------------------------------------------------------------------------
+
+// MultiLimiter enforces several limiters at once, for APIs with layered
+// quotas such as 10/s and 5000/day. Units are reserved on every tier
+// together and the caller waits for the slowest. The faster tiers' units
+// stay reserved during that wait and are given back only if it is
+// cancelled, so a tier that is far behind also slows the others.
+//
+//	api := gs.NewMultiLimiter(
+//	    gs.NewRateLimiter(gs.RateLimitConfig{Rate: 10}),
+//	    gs.NewWindowLimiter(5000, 24*time.Hour),
+//	)
+type MultiLimiter struct {
+	tiers []Limiter
+}
+
+// NewMultiLimiter combines tiers; every unit must pass all of them.
+func NewMultiLimiter(tiers ...Limiter) *MultiLimiter {
+	return &MultiLimiter{tiers: tiers}
+}
+
+// ReserveN reserves n units on every tier and returns the longest delay.
+func (m *MultiLimiter) ReserveN(n float64) (time.Duration, func()) {
+	var d time.Duration
+	cancels := make([]func(), len(m.tiers))
+	for i, t := range m.tiers {
+		var td time.Duration
+		td, cancels[i] = t.ReserveN(n)
+		d = max(d, td)
+	}
+	return d, func() {
+		for _, c := range cancels {
+			c()
+		}
+	}
+}
+
+// WaitN blocks until every tier admits n units.
+func (m *MultiLimiter) WaitN(ctx context.Context, n float64) error { return waitReserved(ctx, m, n) }
+
+// ---------------------------------------------------------------------------
+// WindowLimiter — sliding-window log
+// ---------------------------------------------------------------------------
+
+// WindowLimiter allows at most Limit units in any period of length Window,
+// keeping a log of admitted units. Unlike a token bucket it never lets a
+// burst exceed the limit at a window boundary, at the cost of memory
+// proportional to the number of admissions per window. A single request
+// larger than the limit is counted as the full limit (see ReserveN). Safe
+// for concurrent use.
+type WindowLimiter struct {
+	limit  float64
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	log  []*windowEntry // ordered by time; log[head:] is live
+	head int
+	used float64 // units in log[head:]
+}
+
+type windowEntry struct {
+	at time.Time
+	n  float64
+}
+
+// NewWindowLimiter allows limit units per sliding window.
+func NewWindowLimiter(limit float64, window time.Duration) *WindowLimiter {
+	return &WindowLimiter{limit: max(limit, 1), window: max(window, 1), now: time.Now}
+}
+
+// ReserveN records n units at the earliest time they fit in the window and
+// returns the delay until then. A request cannot be split, so n above the
+// limit is clamped to it: the request waits for an empty window and takes
+// all of it, like a token bucket reservation larger than the burst.
+func (w *WindowLimiter) ReserveN(n float64) (time.Duration, func()) {
+	n = min(clampCost(n), w.limit)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	w.prune(now)
+
+	// Start no earlier than the last reservation, so waits stay in order.
+	at := now
+	if len(w.log) > w.head {
+		at = maxTime(at, w.log[len(w.log)-1].at)
+	}
+	used := w.used
+	for i := w.head; i < len(w.log); i++ {
+		e := w.log[i]
+		if !e.at.After(at.Add(-w.window)) {
+			used -= e.n // already out of the window at time at
+			continue
+		}
+		if used+n <= w.limit {
+			break
+		}
+		at = e.at.Add(w.window)
+		used -= e.n
+	}
+
+	e := &windowEntry{at: at, n: n}
+	w.log = append(w.log, e)
+	w.used += n
+	return at.Sub(now), func() {
+		w.mu.Lock()
+		w.used -= e.n
+		e.n = 0
+		// Drop cancelled reservations at the tail so later ones need not
+		// queue behind them.
+		for len(w.log) > w.head && w.log[len(w.log)-1].n == 0 {
+			w.log[len(w.log)-1] = nil
+			w.log = w.log[:len(w.log)-1]
+		}
+		w.mu.Unlock()
+	}
+}
+
+// WaitN blocks until n units fit in the window.
+func (w *WindowLimiter) WaitN(ctx context.Context, n float64) error { return waitReserved(ctx, w, n) }
+
+// Allow admits one unit if it fits in the window right now.
+func (w *WindowLimiter) Allow() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	w.prune(now)
+	if w.used+1 > w.limit || (len(w.log) > w.head && w.log[len(w.log)-1].at.After(now)) {
+		return false
+	}
+	w.log = append(w.log, &windowEntry{at: now, n: 1})
+	w.used++
+	return true
+}
+
+// prune drops entries that left the window. Called with mu held.
+func (w *WindowLimiter) prune(now time.Time) {
+	cutoff := now.Add(-w.window)
+	for w.head < len(w.log) && !w.log[w.head].at.After(cutoff) {
+		e := w.log[w.head]
+		w.used -= e.n
+		e.n = 0 // a late cancel must not subtract it again
+		w.log[w.head] = nil
+		w.head++
+	}
+	if w.head > 64 && w.head*2 > len(w.log) {
+		n := copy(w.log, w.log[w.head:])
+		clear(w.log[n:])
+		w.log = w.log[:n]
+		w.head = 0
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// ---------------------------------------------------------------------------
+// Weighted pipeline stage
+// ---------------------------------------------------------------------------
+
+// RateLimitCost throttles the pipeline by cost: each element takes cost(v)
+// units from l, e.g. the batch size after PipeBatch or the token count of a
+// prompt. The element is read first, then held until l admits it. A cost
+// above the limiter's burst is allowed and simply waits longer; a negative
+// cost counts as 0, and a NaN cost stops the pipeline with an error.
+//
+//	batches := gs.PipeBatch(events, gs.BatchConfig{Size: 100}).
+//	    RateLimitCost(api, func(b []Event) float64 { return float64(len(b)) })
+func (p *Pipeline[T]) RateLimitCost(l Limiter, cost func(T) float64) *Pipeline[T] {
+	return &Pipeline[T]{
+		source:  &costLimitSource[T]{inner: p.source, limiter: l, cost: cost, ctx: p.ctx, hooks: p.hooks},
+		hooks:   p.hooks,
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+// costLimitSource waits on a Limiter per element. Without a cost function
+// it waits for one unit before pulling, like RateLimit.
+type costLimitSource[T any] struct {
+	inner   Source[T]
+	limiter Limiter
+	cost    func(T) float64
+	ctx     context.Context
+	hooks   *Hooks[T]
+	err     errSlot
+}
+
+func (s *costLimitSource[T]) Next() (T, bool) {
+	var zero T
+	if s.cost == nil {
+		if s.limiter.WaitN(s.ctx, 1) != nil {
+			return zero, false
+		}
+		return s.inner.Next()
+	}
+	for {
+		v, ok := s.inner.Next()
+		if !ok {
+			return zero, false
+		}
+		var n float64
+		if s.hooks != nil && s.hooks.RecoverPanics {
+			var perr *PanicError
+			var abort bool
+			n, perr, abort = callGuarded(s.hooks, s.cost, v)
+			if perr != nil {
+				s.err.set(perr)
+				if abort {
+					return zero, false
+				}
+				continue
+			}
+		} else {
+			n = s.cost(v)
+		}
+		if err := s.limiter.WaitN(s.ctx, n); err != nil {
+			if err == errNaNCost {
+				s.err.set(err)
+			}
+			return zero, false
+		}
+		return v, true
+	}
+}
+
+func (s *costLimitSource[T]) SizeHint() int {
+	if sizer, ok := s.inner.(Sizer); ok {
+		return sizer.SizeHint()
+	}
+	return -1
+}
+
+func (s *costLimitSource[T]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *costLimitSource[T]) Close() error { return closeSource(s.inner) }
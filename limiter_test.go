@@ -0,0 +1,196 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestWindow(limit float64, window time.Duration, clk *fakeClock) *WindowLimiter {
+	w := NewWindowLimiter(limit, window)
+	w.now = clk.Now
+	return w
+}
+
+func TestWindowLimiter_Sliding(t *testing.T) {
+	clk := &fakeClock{t: time.Unix(0, 0)}
+	w := newTestWindow(3, 10*time.Second, clk)
+	for i := 0; i < 3; i++ {
+		if d, _ := w.ReserveN(1); d != 0 {
+			t.Fatalf("reservation %d delayed %v", i, d)
+		}
+	}
+	if d, _ := w.ReserveN(1); d != 10*time.Second {
+		t.Fatalf("4th delayed %v", d)
+	}
+	// Queued behind the 4th, which fits alongside nothing older.
+	clk.Advance(5 * time.Second)
+	if d, _ := w.ReserveN(1); d != 5*time.Second {
+		t.Fatalf("5th delayed %v", d)
+	}
+	if w.Allow() {
+		t.Fatal("Allow jumped the queue")
+	}
+
+	clk.Advance(20 * time.Second)
+	if !w.Allow() || w.used != 1 {
+		t.Fatalf("window not cleared: used %v", w.used)
+	}
+}
+
+func TestWindowLimiter_WeightsAndCancel(t *testing.T) {
+	clk := &fakeClock{t: time.Unix(0, 0)}
+	w := newTestWindow(3, time.Minute, clk)
+	w.ReserveN(2)
+	if d, _ := w.ReserveN(2); d != time.Minute {
+		t.Fatalf("second batch delayed %v", d)
+	}
+	// Larger than the limit: waits for an empty window.
+	d, cancel := w.ReserveN(5)
+	if d != 2*time.Minute {
+		t.Fatalf("oversized delayed %v", d)
+	}
+	cancel()
+	if w.used != 4 {
+		t.Errorf("used after cancel = %v", w.used)
+	}
+	if d, _ := w.ReserveN(1); d != time.Minute {
+		t.Errorf("after cancel, next delayed %v", d)
+	}
+}
+
+func TestWindowLimiter_ClampsOversizedRequest(t *testing.T) {
+	clk := &fakeClock{t: time.Unix(0, 0)}
+	w := newTestWindow(3, time.Minute, clk)
+	if d, _ := w.ReserveN(10); d != 0 {
+		t.Fatalf("oversized on empty window delayed %v", d)
+	}
+	if w.used != 3 {
+		t.Errorf("used = %v, want the limit", w.used)
+	}
+	if d, _ := w.ReserveN(1); d != time.Minute {
+		t.Errorf("next delayed %v", d)
+	}
+}
+
+func TestMultiLimiter(t *testing.T) {
+	clk := &fakeClock{t: time.Unix(0, 0)}
+	perSec := NewRateLimiter(RateLimitConfig{Rate: 1000, Burst: 10})
+	perHour := newTestWindow(2, time.Hour, clk)
+	m := NewMultiLimiter(perSec, perHour)
+
+	m.ReserveN(1)
+	m.ReserveN(1)
+	d, cancel := m.ReserveN(1)
+	if d != time.Hour {
+		t.Fatalf("third delayed %v", d)
+	}
+	cancel()
+	if perHour.used != 2 {
+		t.Errorf("window not refunded: %v", perHour.used)
+	}
+	perSec.bucket.mu.Lock()
+	tokens := perSec.bucket.tokens
+	perSec.bucket.mu.Unlock()
+	if tokens < 8 {
+		t.Errorf("bucket not refunded: %v tokens", tokens)
+	}
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelCtx()
+	if err := m.WaitN(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitN = %v", err)
+	}
+	if perHour.used != 2 {
+		t.Errorf("cancelled wait kept its units: %v", perHour.used)
+	}
+}
+
+func TestRateLimitCost(t *testing.T) {
+	// 100 units/s, burst 10: batches of 10 go out at 0, ~100ms, ~200ms.
+	l := NewRateLimiter(RateLimitConfig{Rate: 100, Burst: 10})
+	start := time.Now()
+	out := PipeChunk(FromRange(0, 30), 10).
+		RateLimitCost(l, func(b []int) float64 { return float64(len(b)) }).
+		Collect()
+	elapsed := time.Since(start)
+	if len(out) != 3 {
+		t.Fatalf("got %d batches", len(out))
+	}
+	if elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("elapsed %v", elapsed)
+	}
+}
+
+func TestRateLimitCost_PanicRecovery(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 1000})
+	p := FromSlice([]int{1, -1, 2}).WithPanicRecovery().
+		RateLimitCost(l, func(n int) float64 {
+			if n < 0 {
+				panic("negative cost")
+			}
+			return float64(n)
+		})
+	if out := p.Collect(); len(out) != 2 {
+		t.Errorf("got %v", out)
+	}
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
+
+func TestLimiter_NegativeAndNaNCost(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 1, Interval: time.Hour, Burst: 2})
+	clk := &fakeClock{t: time.Unix(0, 0)}
+	w := newTestWindow(2, time.Hour, clk)
+	for name, lim := range map[string]Limiter{"RateLimiter": l, "WindowLimiter": w} {
+		// A negative cost must not add units beyond the limit.
+		if d, _ := lim.ReserveN(-5); d != 0 {
+			t.Errorf("%s: ReserveN(-5) waits %v", name, d)
+		}
+		if err := lim.WaitN(nil, math.NaN()); err == nil {
+			t.Errorf("%s: WaitN(NaN) accepted", name)
+		}
+		if d, cancel := lim.ReserveN(math.NaN()); d != 0 {
+			t.Errorf("%s: ReserveN(NaN) waits %v", name, d)
+		} else {
+			cancel()
+		}
+		// The limit still holds: two units pass, the third waits.
+		if d, _ := lim.ReserveN(2); d != 0 {
+			t.Errorf("%s: burst refused, waits %v", name, d)
+		}
+		if d, _ := lim.ReserveN(1); d <= 0 {
+			t.Errorf("%s: admitted beyond the limit (delay %v)", name, d)
+		}
+	}
+}
+
+func TestRateLimitCost_NaN(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 1000})
+	p := FromSlice([]int{1, 2, 3}).RateLimitCost(l, func(n int) float64 {
+		if n == 2 {
+			return math.NaN()
+		}
+		return 1
+	})
+	if out := p.Collect(); len(out) != 1 {
+		t.Errorf("got %v", out)
+	}
+	if p.Err() == nil {
+		t.Error("NaN cost not reported")
+	}
+}
+
+func TestRateLimitWith_WindowLimiter(t *testing.T) {
+	w := NewWindowLimiter(5, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	out := FromRange(0, 10).WithContext(ctx).RateLimitWith(w).Collect()
+	if len(out) != 5 {
+		t.Errorf("got %d elements within the window", len(out))
+	}
+}
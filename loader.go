@@ -0,0 +1,298 @@
+package gosplice
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// ---------------------------------------------------------------------------
+// BatchLoader
+// ---------------------------------------------------------------------------
+
+// DefaultBatchCacheSize bounds the cache of the loader PipeMapBatched
+// creates.
+const DefaultBatchCacheSize = 10_000
+
+// BatchLoaderConfig controls a BatchLoader.
+type BatchLoaderConfig struct {
+	// CacheSize bounds the number of cached results, evicting the least
+	// recently used. Zero caches without bound, which suits finite inputs;
+	// negative disables the cache and keeps only in-flight deduplication.
+	// Errors are never cached.
+	CacheSize int
+}
+
+// BatchLoader turns a bulk lookup (SELECT ... WHERE id IN (...), a batch
+// GET endpoint) into per-key results, DataLoader style: keys already cached
+// are answered from memory, keys being fetched by another caller wait for
+// that fetch (singleflight), and only the rest reach fn, once each.
+// Safe for concurrent use; share one loader between pipelines or parallel
+// workers so they deduplicate against each other.
+type BatchLoader[K comparable, V any] struct {
+	fn   func([]K) ([]V, error)
+	size int
+
+	mu    sync.Mutex
+	cache map[K]*list.Element // of *loaderEntry
+	lru   *list.List          // front = most recent
+	calls map[K]*loadCall[V]
+}
+
+type loaderEntry[K comparable, V any] struct {
+	key K
+	val V
+}
+
+// loadCall is one in-flight fetch of a key; done is closed when v and err
+// are set.
+type loadCall[V any] struct {
+	done chan struct{}
+	v    V
+	err  error
+}
+
+// NewBatchLoader creates a loader around fn, which must return one value
+// per key, in the order of keys.
+func NewBatchLoader[K comparable, V any](cfg BatchLoaderConfig, fn func([]K) ([]V, error)) *BatchLoader[K, V] {
+	return &BatchLoader[K, V]{
+		fn:    fn,
+		size:  cfg.CacheSize,
+		cache: make(map[K]*list.Element),
+		lru:   list.New(),
+		calls: make(map[K]*loadCall[V]),
+	}
+}
+
+// LoadMany returns the value or error for every key, in order. Keys that
+// are neither cached nor in flight are fetched with a single call to fn,
+// duplicates included once. A panic in fn is recovered and returned as a
+// *PanicError for each key it was fetching, so concurrent waiters are never
+// left hanging.
+func (l *BatchLoader[K, V]) LoadMany(keys []K) ([]V, []error) {
+	vals := make([]V, len(keys))
+	errs := make([]error, len(keys))
+	waits := make([]*loadCall[V], len(keys))
+	var fetch []K
+	var own []*loadCall[V]
+
+	l.mu.Lock()
+	for i, k := range keys {
+		if e, ok := l.cache[k]; ok {
+			l.lru.MoveToFront(e)
+			vals[i] = e.Value.(*loaderEntry[K, V]).val
+			continue
+		}
+		c := l.calls[k]
+		if c == nil {
+			c = &loadCall[V]{done: make(chan struct{})}
+			l.calls[k] = c
+			fetch = append(fetch, k)
+			own = append(own, c)
+		}
+		waits[i] = c
+	}
+	l.mu.Unlock()
+
+	if len(fetch) > 0 {
+		l.fetch(fetch, own)
+	}
+	for i, c := range waits {
+		if c != nil {
+			<-c.done
+			vals[i], errs[i] = c.v, c.err
+		}
+	}
+	return vals, errs
+}
+
+// Load returns the value for one key. Prefer LoadMany: each Load call that
+// misses the cache is a separate call to fn.
+func (l *BatchLoader[K, V]) Load(key K) (V, error) {
+	vals, errs := l.LoadMany([]K{key})
+	return vals[0], errs[0]
+}
+
+// Clear drops key from the cache, so the next load fetches it again.
+func (l *BatchLoader[K, V]) Clear(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.cache[key]; ok {
+		l.lru.Remove(e)
+		delete(l.cache, key)
+	}
+}
+
+// fetch calls fn for keys and completes their calls, even if fn panics.
+func (l *BatchLoader[K, V]) fetch(keys []K, calls []*loadCall[V]) {
+	var vals []V
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+		if err == nil && len(vals) != len(keys) {
+			err = fmt.Errorf("gosplice: BatchLoader: fn returned %d values for %d keys", len(vals), len(keys))
+		}
+		l.mu.Lock()
+		for i, k := range keys {
+			c := calls[i]
+			delete(l.calls, k)
+			if err != nil {
+				c.err = err
+			} else {
+				c.v = vals[i]
+				l.store(k, vals[i])
+			}
+			close(c.done)
+		}
+		l.mu.Unlock()
+	}()
+	vals, err = l.fn(keys)
+}
+
+// store caches v under k, evicting the least recently used entry when
+// full. Called with mu held.
+func (l *BatchLoader[K, V]) store(k K, v V) {
+	if l.size < 0 {
+		return
+	}
+	l.cache[k] = l.lru.PushFront(&loaderEntry[K, V]{key: k, val: v})
+	if l.size > 0 && l.lru.Len() > l.size {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.cache, oldest.Value.(*loaderEntry[K, V]).key)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Pipeline stage
+// ---------------------------------------------------------------------------
+
+// PipeMapBatched maps each element through a bulk lookup: elements are
+// grouped as by PipeBatch (Size, MaxWait), fn is called once per batch with
+// the distinct keys not seen before, and results come out one per element
+// in input order. The DefaultBatchCacheSize most recently used results are
+// cached for the life of the stage, so a repeated key usually costs one
+// lookup in total while memory stays bounded on an endless stream. Use
+// PipeMapBatchedWith for another cache size, including an unbounded one.
+//
+// Failed lookups go through the error handler per element like PipeMapErr:
+// Skip drops the element, Retry fetches it again (with the batch's other
+// retries, up to MaxRetries attempts), Abort stops the stream. A panic in
+// fn is always recovered, reported to the handler and by Err().
+//
+//	users := gs.PipeMapBatched(userIDs, gs.BatchConfig{Size: 100, MaxWait: 10 * time.Millisecond},
+//	    func(ids []int64) ([]User, error) { return db.UsersByID(ctx, ids) })
+func PipeMapBatched[K comparable, V any](p *Pipeline[K], cfg BatchConfig, fn func([]K) ([]V, error)) *Pipeline[V] {
+	return PipeMapBatchedWith(p, cfg, NewBatchLoader(BatchLoaderConfig{CacheSize: DefaultBatchCacheSize}, fn))
+}
+
+// PipeMapBatchedWith is PipeMapBatched with a caller-owned BatchLoader, to
+// size its cache or share it between stages.
+func PipeMapBatchedWith[K comparable, V any](p *Pipeline[K], cfg BatchConfig, l *BatchLoader[K, V]) *Pipeline[V] {
+	hooks := p.hooks
+	batches := PipeBatch(p, cfg)
+	return &Pipeline[V]{
+		source: &batchedMapSource[K, V]{
+			inner: batches.source, loader: l, hooks: hooks,
+			hasErr: hooks.hasError(), maxRetries: max(hooks.MaxRetries, 1),
+		},
+		hooks:   inheritHooks[V](hooks),
+		ctx:     batches.ctx,
+		cancel:  batches.cancel,
+		stop:    batches.stop,
+		ctxNoop: batches.ctxNoop,
+	}
+}
+
+type batchedMapSource[K comparable, V any] struct {
+	inner      Source[[]K]
+	loader     *BatchLoader[K, V]
+	hooks      *Hooks[K]
+	hasErr     bool
+	maxRetries int
+	out        []V
+	done       bool
+	err        errSlot
+}
+
+func (s *batchedMapSource[K, V]) Next() (V, bool) {
+	var zero V
+	for len(s.out) == 0 {
+		if s.done {
+			return zero, false
+		}
+		batch, ok := s.inner.Next()
+		if !ok {
+			return zero, false
+		}
+		s.out = s.resolve(batch)
+	}
+	v := s.out[0]
+	s.out[0] = zero
+	s.out = s.out[1:]
+	return v, true
+}
+
+// resolve loads a batch, retrying failed keys as the error handler asks,
+// and returns the results to emit in order. On Abort only the results
+// before the aborted element are kept.
+func (s *batchedMapSource[K, V]) resolve(batch []K) []V {
+	vals := make([]V, len(batch))
+	loaded := make([]bool, len(batch))
+	stop := len(batch)
+
+	pending := make([]int, len(batch))
+	for i := range pending {
+		pending[i] = i
+	}
+	keys := make([]K, 0, len(batch))
+	for attempt := 0; len(pending) > 0 && attempt < s.maxRetries; attempt++ {
+		keys = keys[:0]
+		for _, i := range pending {
+			keys = append(keys, batch[i])
+		}
+		got, errs := s.loader.LoadMany(keys)
+		var retry []int
+		for j, i := range pending {
+			if i >= stop {
+				break
+			}
+			if errs[j] == nil {
+				vals[i], loaded[i] = got[j], true
+				continue
+			}
+			if perr, ok := errs[j].(*PanicError); ok {
+				s.err.set(perr)
+			}
+			if !s.hasErr {
+				continue
+			}
+			switch s.hooks.handleError(errs[j], batch[i], attempt+1) {
+			case Retry:
+				retry = append(retry, i)
+			case Abort:
+				stop, s.done = i, true
+			}
+		}
+		pending = retry
+	}
+
+	out := vals[:0]
+	for i := 0; i < stop; i++ {
+		if loaded[i] {
+			out = append(out, vals[i])
+		}
+	}
+	return out
+}
+
+func (s *batchedMapSource[K, V]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *batchedMapSource[K, V]) Close() error { return closeSource(s.inner) }
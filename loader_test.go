@@ -0,0 +1,223 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lookupLog is a bulk lookup that records the keys of every call.
+type lookupLog struct {
+	mu    sync.Mutex
+	calls [][]int
+}
+
+func (l *lookupLog) fetch(ids []int) ([]string, error) {
+	l.mu.Lock()
+	l.calls = append(l.calls, slices.Clone(ids))
+	l.mu.Unlock()
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = "user" + strconv.Itoa(id)
+	}
+	return out, nil
+}
+
+func TestPipeMapBatched_OrderAndDedup(t *testing.T) {
+	var log lookupLog
+	in := []int{3, 1, 3, 2, 1, 4, 3, 5}
+	out := PipeMapBatched(FromSlice(in), BatchConfig{Size: 4}, log.fetch).Collect()
+
+	var want []string
+	for _, id := range in {
+		want = append(want, "user"+strconv.Itoa(id))
+	}
+	if !slices.Equal(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+	// Batch 1 fetches 3, 1, 2 once each; batch 2 only the new keys.
+	wantCalls := [][]int{{3, 1, 2}, {4, 5}}
+	if !slices.EqualFunc(log.calls, wantCalls, slices.Equal) {
+		t.Errorf("calls %v, want %v", log.calls, wantCalls)
+	}
+}
+
+func TestPipeMapBatched_DefaultCacheIsBounded(t *testing.T) {
+	var log lookupLog
+	// Key 0 is evicted by the keys after it, then fetched again.
+	in := append(makeRange(DefaultBatchCacheSize+1000), 0)
+	out := PipeMapBatched(FromSlice(in), BatchConfig{Size: 1000}, log.fetch).Collect()
+	if len(out) != len(in) || out[len(out)-1] != "user0" {
+		t.Fatalf("got %d results ending in %q", len(out), out[len(out)-1])
+	}
+	if last := log.calls[len(log.calls)-1]; !slices.Equal(last, []int{0}) {
+		t.Errorf("last call %v, want [0]", last)
+	}
+}
+
+func TestPipeMapBatched_MaxWait(t *testing.T) {
+	var log lookupLog
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		ch <- 2
+		time.Sleep(50 * time.Millisecond)
+		ch <- 2
+		ch <- 3
+		close(ch)
+	}()
+	out := PipeMapBatched(FromChannel(ch), BatchConfig{Size: 100, MaxWait: 10 * time.Millisecond}, log.fetch).Collect()
+	if !slices.Equal(out, []string{"user1", "user2", "user2", "user3"}) {
+		t.Errorf("got %v", out)
+	}
+	if len(log.calls) != 2 {
+		t.Errorf("calls %v", log.calls)
+	}
+}
+
+func TestPipeMapBatched_MaxWaitWithContextAfter(t *testing.T) {
+	var log lookupLog
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := PipeMapBatched(FromSlice([]int{1, 2, 3}), BatchConfig{Size: 2, MaxWait: time.Second}, log.fetch).
+		WithContext(ctx).Collect()
+	if want := []string{"user1", "user2", "user3"}; !slices.Equal(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+}
+
+func TestPipeMapBatched_ErrorsPerElement(t *testing.T) {
+	errDown := errors.New("db down")
+	var calls atomic.Int32
+	fetch := func(ids []int) ([]string, error) {
+		if calls.Add(1) == 1 {
+			return nil, errDown
+		}
+		return (&lookupLog{}).fetch(ids)
+	}
+	var failed []int
+	out := PipeMapBatched(
+		FromSlice([]int{1, 2, 3}).
+			WithErrorHandler(func(err error, id int, attempt int) ErrorAction {
+				failed = append(failed, id)
+				return Retry
+			}),
+		BatchConfig{Size: 3}, fetch).Collect()
+	if !slices.Equal(out, []string{"user1", "user2", "user3"}) || calls.Load() != 2 {
+		t.Errorf("got %v after %d calls", out, calls.Load())
+	}
+	if !slices.Equal(failed, []int{1, 2, 3}) {
+		t.Errorf("handler saw %v", failed)
+	}
+}
+
+func TestPipeMapBatched_SkipAndAbort(t *testing.T) {
+	fetch := func(ids []int) ([]string, error) {
+		if slices.Contains(ids, 3) {
+			return nil, errors.New("bad id")
+		}
+		return (&lookupLog{}).fetch(ids)
+	}
+	// A failed call fails every element of its batch: 4 goes down with 3.
+	var skipped []int
+	out := PipeMapBatched(FromSlice([]int{1, 2, 3, 4}).
+		WithErrorHook(func(err error, id int) { skipped = append(skipped, id) }),
+		BatchConfig{Size: 2}, fetch).Collect()
+	if !slices.Equal(out, []string{"user1", "user2"}) || !slices.Equal(skipped, []int{3, 4}) {
+		t.Errorf("skip: got %v, skipped %v", out, skipped)
+	}
+
+	out = PipeMapBatched(FromSlice([]int{1, 2, 3, 4, 5}).
+		WithErrorHandler(func(error, int, int) ErrorAction { return Abort }),
+		BatchConfig{Size: 5}, func(ids []int) ([]string, error) {
+			return nil, errors.New("down")
+		}).Collect()
+	if len(out) != 0 {
+		t.Errorf("abort: got %v", out)
+	}
+}
+
+func TestPipeMapBatched_PanicRecovered(t *testing.T) {
+	p := PipeMapBatched(FromSlice([]int{1, 2}), BatchConfig{Size: 2}, func(ids []int) ([]string, error) {
+		panic("driver bug")
+	})
+	if out := p.Collect(); len(out) != 0 {
+		t.Errorf("got %v", out)
+	}
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
+
+func TestBatchLoader_Singleflight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	l := NewBatchLoader(BatchLoaderConfig{}, func(ids []int) ([]int, error) {
+		calls.Add(1)
+		<-release
+		out := make([]int, len(ids))
+		for i, id := range ids {
+			out[i] = id * 10
+		}
+		return out, nil
+	})
+
+	var wg sync.WaitGroup
+	results := make([]int, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Load(7)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("fn called %d times", calls.Load())
+	}
+	for _, v := range results {
+		if v != 70 {
+			t.Fatalf("results %v", results)
+		}
+	}
+}
+
+func TestBatchLoader_CacheBoundAndClear(t *testing.T) {
+	var log lookupLog
+	l := NewBatchLoader(BatchLoaderConfig{CacheSize: 2}, log.fetch)
+	l.LoadMany([]int{1, 2})
+	l.LoadMany([]int{3, 2}) // evicts 1
+	l.LoadMany([]int{1, 2}) // refetches 1, evicts 3
+	l.Clear(2)
+	l.LoadMany([]int{2})
+	wantCalls := [][]int{{1, 2}, {3}, {1}, {2}}
+	if !slices.EqualFunc(log.calls, wantCalls, slices.Equal) {
+		t.Errorf("calls %v, want %v", log.calls, wantCalls)
+	}
+
+	noCache := NewBatchLoader(BatchLoaderConfig{CacheSize: -1}, (&lookupLog{}).fetch)
+	noCache.Load(1)
+	if len(noCache.cache) != 0 {
+		t.Errorf("negative CacheSize cached %d entries", len(noCache.cache))
+	}
+}
+
+func TestBatchLoader_WrongResultCount(t *testing.T) {
+	l := NewBatchLoader(BatchLoaderConfig{}, func(ids []int) ([]int, error) { return []int{1}, nil })
+	_, errs := l.LoadMany([]int{1, 2})
+	if errs[0] == nil || errs[1] == nil {
+		t.Errorf("errs %v", errs)
+	}
+}
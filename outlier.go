@@ -0,0 +1,207 @@
+package gosplice
+
+import (
+	"context"
+	"math"
+	"slices"
+)
+
+// Annotated is an element with its anomaly score. Score is signed —
+// positive above the expected value, negative below — and IsOutlier is set
+// when |Score| exceeds the detector's threshold. Route anomalies with
+// Filter, Partition or Peek on IsOutlier.
+type Annotated[T any] struct {
+	Value     T
+	Score     float64
+	IsOutlier bool
+}
+
+// PipeOutliersIQR flags values outside Tukey's fences [Q1 - k·IQR, Q3 + k·IQR]
+// (k = 1.5 is the usual choice, 3 for "far out"). Score is the distance
+// beyond Q3 (or below Q1) in IQRs, 0 inside the box.
+//
+// Quartiles need the whole input, so this is a barrier like PipeSort: the
+// input is read into memory on the first pull, then emitted in order.
+func PipeOutliersIQR[T any](p *Pipeline[T], valueFn func(T) float64, k float64) *Pipeline[Annotated[T]] {
+	return annotateAll(p, valueFn, func(items []T, vals []float64) []Annotated[T] {
+		sorted := slices.Clone(vals)
+		slices.Sort(sorted)
+		q1 := percentileFromSorted(sorted, 25)
+		q3 := percentileFromSorted(sorted, 75)
+		iqr := q3 - q1
+
+		out := make([]Annotated[T], len(items))
+		for i, x := range vals {
+			var score float64
+			switch {
+			case x > q3:
+				score = scaledDistance(x-q3, iqr)
+			case x < q1:
+				score = -scaledDistance(q1-x, iqr)
+			}
+			out[i] = Annotated[T]{Value: items[i], Score: score, IsOutlier: math.Abs(score) > k}
+		}
+		return out
+	})
+}
+
+// PipeOutliersMAD flags values by their modified z-score,
+// 0.6745·(x - median) / MAD, where MAD is the median absolute deviation
+// (Iglewicz and Hoaglin; 3.5 is the usual threshold). Robust to the
+// outliers themselves, unlike a mean/stddev z-score.
+//
+// Like PipeOutliersIQR this is a barrier that reads the input first.
+func PipeOutliersMAD[T any](p *Pipeline[T], valueFn func(T) float64, threshold float64) *Pipeline[Annotated[T]] {
+	return annotateAll(p, valueFn, func(items []T, vals []float64) []Annotated[T] {
+		sorted := slices.Clone(vals)
+		slices.Sort(sorted)
+		med := percentileFromSorted(sorted, 50)
+		for i, x := range vals {
+			sorted[i] = math.Abs(x - med)
+		}
+		slices.Sort(sorted)
+		mad := percentileFromSorted(sorted, 50)
+
+		out := make([]Annotated[T], len(items))
+		for i, x := range vals {
+			score := 0.6745 * signedScaled(x-med, mad)
+			out[i] = Annotated[T]{Value: items[i], Score: score, IsOutlier: math.Abs(score) > threshold}
+		}
+		return out
+	})
+}
+
+// PipeOutliersZScore is a streaming detector: each value is scored against
+// the mean and standard deviation of the values before it,
+// (x - mean) / stddev. Nothing is flagged during the first warmup elements
+// while the estimate settles. O(1) memory.
+func PipeOutliersZScore[T any](p *Pipeline[T], valueFn func(T) float64, threshold float64, warmup int) *Pipeline[Annotated[T]] {
+	return PipeScan(p, VarianceAcc{}, func(acc VarianceAcc, v T) (VarianceAcc, Annotated[T]) {
+		x := valueFn(v)
+		var score float64
+		if acc.Count > 0 {
+			score = signedScaled(x-acc.Mean, acc.StdDev())
+		}
+		out := Annotated[T]{Value: v, Score: score, IsOutlier: acc.Count >= warmup && math.Abs(score) > threshold}
+		acc.Add(x)
+		return acc, out
+	})
+}
+
+// PipeOutliersEWMA is PipeOutliersZScore against an exponentially weighted
+// mean and standard deviation (see PipeEWMA), so the baseline follows
+// slow drifts and seasonality while sudden jumps are still flagged.
+func PipeOutliersEWMA[T any](p *Pipeline[T], valueFn func(T) float64, alpha, threshold float64, warmup int) *Pipeline[Annotated[T]] {
+	alpha = clampAlpha(alpha)
+	return PipeScan(p, ewState{}, func(s ewState, v T) (ewState, Annotated[T]) {
+		x := valueFn(v)
+		var score float64
+		if s.n > 0 {
+			score = signedScaled(x-s.mean, s.std())
+		}
+		out := Annotated[T]{Value: v, Score: score, IsOutlier: s.n >= warmup && math.Abs(score) > threshold}
+		s.add(x, alpha)
+		return s, out
+	})
+}
+
+// scaledDistance returns d/scale for d ≥ 0; a zero scale makes any nonzero
+// distance infinitely far.
+func scaledDistance(d, scale float64) float64 {
+	if scale == 0 {
+		if d == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return d / scale
+}
+
+func signedScaled(d, scale float64) float64 {
+	if d < 0 {
+		return -scaledDistance(-d, scale)
+	}
+	return scaledDistance(d, scale)
+}
+
+// annotateAll builds a lazy barrier stage: the input is read on the first
+// pull, valueFn is applied to every element and fn annotates them as a
+// whole. With WithPanicRecovery an element whose valueFn panics goes
+// through the error handler and is left out, as in PipeScan.
+func annotateAll[T any](p *Pipeline[T], valueFn func(T) float64, fn func([]T, []float64) []Annotated[T]) *Pipeline[Annotated[T]] {
+	return &Pipeline[Annotated[T]]{
+		source: &barrierSource[T, Annotated[T]]{
+			inner: p.source, ctx: p.ctx, hooks: p.hooks, valueFn: valueFn, fn: fn,
+		},
+		hooks:   inheritHooks[Annotated[T]](p.hooks),
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+type barrierSource[T any, U any] struct {
+	inner   Source[T]
+	ctx     context.Context
+	hooks   *Hooks[T]
+	valueFn func(T) float64
+	fn      func([]T, []float64) []U
+	out     []U
+	idx     int
+	inited  bool
+	err     errSlot
+}
+
+func (s *barrierSource[T, U]) Next() (U, bool) {
+	if !s.inited {
+		s.inited = true
+		items, cancelled := drainSourceCtx(s.inner, s.ctx)
+		if !cancelled {
+			items, vals := s.values(items)
+			s.out = s.fn(items, vals)
+		}
+	}
+	if s.idx >= len(s.out) {
+		var zero U
+		return zero, false
+	}
+	v := s.out[s.idx]
+	s.idx++
+	return v, true
+}
+
+// values applies valueFn to items, dropping the elements whose call
+// panicked (and everything from an aborted one on) under RecoverPanics.
+func (s *barrierSource[T, U]) values(items []T) ([]T, []float64) {
+	vals := make([]float64, 0, len(items))
+	if !s.hooks.RecoverPanics {
+		for _, v := range items {
+			vals = append(vals, s.valueFn(v))
+		}
+		return items, vals
+	}
+	kept := items[:0]
+	for _, v := range items {
+		x, perr, abort := callGuarded(s.hooks, s.valueFn, v)
+		if perr != nil {
+			s.err.set(perr)
+			if abort {
+				break
+			}
+			continue
+		}
+		kept = append(kept, v)
+		vals = append(vals, x)
+	}
+	return kept, vals
+}
+
+func (s *barrierSource[T, U]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *barrierSource[T, U]) Close() error { return closeSource(s.inner) }
@@ -0,0 +1,155 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func flagged[T any](as []Annotated[T]) []T {
+	var out []T
+	for _, a := range as {
+		if a.IsOutlier {
+			out = append(out, a.Value)
+		}
+	}
+	return out
+}
+
+func idf(x float64) float64 { return x }
+
+func TestPipeOutliersIQR(t *testing.T) {
+	data := []float64{10, 12, 11, 13, 12, 11, 100, 12, -50, 11}
+	out := PipeOutliersIQR(FromSlice(data), idf, 1.5).Collect()
+	if len(out) != len(data) {
+		t.Fatalf("got %d elements", len(out))
+	}
+	if got := flagged(out); !slices.Equal(got, []float64{100, -50}) {
+		t.Errorf("flagged %v", got)
+	}
+	// Q1=11, Q3=12 → IQR 1: 100 is 88 IQRs above Q3, -50 is 61 below Q1,
+	// 10 is one below (inside the fence) and 12 is inside the box.
+	if out[6].Score != 88 || out[8].Score != -61 || out[0].Score != -1 || out[1].Score != 0 {
+		t.Errorf("scores %v %v %v %v", out[6].Score, out[8].Score, out[0].Score, out[1].Score)
+	}
+	for i, a := range out {
+		if a.Value != data[i] {
+			t.Fatalf("order changed at %d", i)
+		}
+	}
+}
+
+func TestPipeOutliersIQR_IsLazy(t *testing.T) {
+	pulled := 0
+	p := FromSlice([]float64{1, 2, 3}).Peek(func(float64) { pulled++ })
+	r := PipeOutliersIQR(p, idf, 1.5)
+	if pulled != 0 {
+		t.Fatal("input read before the first pull")
+	}
+	r.Collect()
+	if pulled != 3 {
+		t.Errorf("pulled %d", pulled)
+	}
+}
+
+func TestPipeOutliersMAD(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5, 6, 1000}
+	out := PipeOutliersMAD(FromSlice(data), idf, 3.5).Collect()
+	if got := flagged(out); !slices.Equal(got, []float64{1000}) {
+		t.Errorf("flagged %v", got)
+	}
+	// median 4, MAD 2 → modified z of 1 is 0.6745·(-3)/2.
+	if !approxEqual(out[0].Score, 0.6745*-1.5, eps) {
+		t.Errorf("score of 1 = %v", out[0].Score)
+	}
+
+	flat := PipeOutliersMAD(FromSlice([]float64{5, 5, 5, 6}), idf, 3.5).Collect()
+	if !math.IsInf(flat[3].Score, 1) || !flat[3].IsOutlier || flat[0].IsOutlier {
+		t.Errorf("zero MAD: %+v", flat)
+	}
+}
+
+func TestPipeOutliersZScore(t *testing.T) {
+	data := []float64{10, 11, 9, 10, 11, 9, 10, 50, 10, 11}
+	out := PipeOutliersZScore(FromSlice(data), idf, 3, 5).Collect()
+	if got := flagged(out); !slices.Equal(got, []float64{50}) {
+		t.Errorf("flagged %v", got)
+	}
+
+	// During warm-up even a large jump is not flagged.
+	early := PipeOutliersZScore(FromSlice([]float64{10, 11, 500}), idf, 3, 5).Collect()
+	if flagged(early) != nil || early[2].Score <= 3 {
+		t.Errorf("warm-up: %+v", early)
+	}
+}
+
+func TestPipeOutliersEWMA_FollowsDrift(t *testing.T) {
+	// A slow ramp is tracked by the EWMA baseline; a single spike is not.
+	var data []float64
+	for i := 0; i < 200; i++ {
+		data = append(data, float64(i)+float64(i%3))
+	}
+	data[150] = 1000
+	out := PipeOutliersEWMA(FromSlice(data), idf, 0.3, 4, 10).Collect()
+	if got := flagged(out); !slices.Equal(got, []float64{1000}) {
+		t.Errorf("flagged %v", got)
+	}
+	zs := PipeOutliersZScore(FromSlice(data[:150]), idf, 2, 10).Collect()
+	if len(flagged(zs)) == 0 {
+		t.Error("expected the plain z-score to be fooled by the ramp")
+	}
+}
+
+func TestPipeOutliersIQR_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := FromSlice([]float64{1, 2, 3}).WithContext(ctx)
+	if out := PipeOutliersIQR(p, idf, 1.5).Collect(); len(out) != 0 {
+		t.Errorf("got %v after cancel", out)
+	}
+}
+
+func TestPipeOutliersZScore_PanicRecovery(t *testing.T) {
+	p := FromSlice([]float64{1, 2, -1, 3}).WithPanicRecovery()
+	r := PipeOutliersZScore(p, func(x float64) float64 {
+		if x < 0 {
+			panic("negative")
+		}
+		return x
+	}, 3, 0)
+	out := r.Collect()
+	// The panicking element is dropped and does not enter the baseline:
+	// 3 is scored against {1, 2} (mean 1.5, std 0.5).
+	if len(out) != 3 || out[2].Score != 3 {
+		t.Errorf("got %+v", out)
+	}
+	var pe *PanicError
+	if !errors.As(r.Err(), &pe) {
+		t.Errorf("Err() = %v", r.Err())
+	}
+}
+
+func TestPipeOutliersIQR_PanicRecovery(t *testing.T) {
+	var failed []float64
+	p := FromSlice([]float64{1, 2, -1, 3, 4}).WithPanicRecovery().
+		WithErrorHook(func(err error, x float64) { failed = append(failed, x) })
+	r := PipeOutliersIQR(p, func(x float64) float64 {
+		if x < 0 {
+			panic("negative")
+		}
+		return x
+	}, 1.5)
+	var got []float64
+	for _, a := range r.Collect() {
+		got = append(got, a.Value)
+	}
+	if !slices.Equal(got, []float64{1, 2, 3, 4}) || !slices.Equal(failed, []float64{-1}) {
+		t.Errorf("got %v, failed %v", got, failed)
+	}
+	var pe *PanicError
+	if !errors.As(r.Err(), &pe) {
+		t.Errorf("Err() = %v", r.Err())
+	}
+}
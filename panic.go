@@ -0,0 +1,122 @@
+package gosplice
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// PanicError wraps a value recovered from a panic in a user function
+// (Filter predicate, PipeMap fn, parallel worker...) together with the
+// stack of the panicking goroutine.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func newPanicError(v any) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("gosplice: recovered panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, so errors.Is/As see through it.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// WithPanicRecovery makes sequential stages built on this pipeline (Filter,
+// PipeMap, PipeMapErr, PipeFlatMap and everything derived from them)
+// recover panics in user functions. A recovered panic becomes a *PanicError
+// that goes through the ErrorHandler / ErrorHooks like any other element
+// error (Skip drops the element, Retry re-runs fn, Abort stops the pipeline),
+// and the first one is reported by Err().
+//
+// Parallel stages and the PipeBatch timeout reader always recover, since a
+// panic in a background goroutine cannot be caught by the caller.
+func (p *Pipeline[T]) WithPanicRecovery() *Pipeline[T] {
+	p.hooks.RecoverPanics = true
+	return p
+}
+
+// callRecover runs fn(v), converting a panic into a *PanicError.
+func callRecover[T any, U any](fn func(T) U, v T) (result U, perr *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			perr = newPanicError(r)
+		}
+	}()
+	return fn(v), nil
+}
+
+// nextRecover calls src.Next, converting a panic in an upstream stage into
+// a *PanicError. Used by stages that pull from the source on a background
+// goroutine.
+func nextRecover[T any](src Source[T]) (v T, ok bool, perr *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			perr = newPanicError(r)
+		}
+	}()
+	v, ok = src.Next()
+	return v, ok, nil
+}
+
+// callGuarded runs fn(v) with panic recovery and routes each panic through
+// the error handler. Retry re-runs fn until MaxRetries attempts are used.
+// A nil perr means fn succeeded; otherwise the element is dropped and, if
+// abort is true, the stage must stop.
+func callGuarded[T any, U any](h *Hooks[T], fn func(T) U, v T) (result U, perr *PanicError, abort bool) {
+	for attempt := 1; ; attempt++ {
+		result, perr = callRecover(fn, v)
+		if perr == nil {
+			return result, nil, false
+		}
+		var zero U
+		switch h.handleError(perr, v, attempt) {
+		case Abort:
+			return zero, perr, true
+		case Retry:
+			if attempt < h.MaxRetries {
+				continue
+			}
+		}
+		return zero, perr, false
+	}
+}
+
+// errSlot records the first error from a stage. Safe for concurrent use,
+// so background goroutines can report into it.
+type errSlot struct {
+	err atomic.Pointer[error]
+}
+
+func (s *errSlot) set(err error) {
+	if err == nil {
+		return
+	}
+	e := new(error)
+	*e = err
+	s.err.CompareAndSwap(nil, e)
+}
+
+func (s *errSlot) get() error {
+	if ptr := s.err.Load(); ptr != nil {
+		return *ptr
+	}
+	return nil
+}
+
+// innerErr returns src.Err() if src reports errors, so wrapping stages can
+// surface errors from further upstream through Pipeline.Err().
+func innerErr(src any) error {
+	if se, ok := src.(sourceWithErr); ok {
+		return se.Err()
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package gosplice
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPanicError(t *testing.T) {
+	base := errors.New("boom")
+	pe := newPanicError(base)
+	if !errors.Is(pe, base) {
+		t.Error("expected PanicError to unwrap to the panic value")
+	}
+	if !strings.Contains(pe.Error(), "boom") || len(pe.Stack) == 0 {
+		t.Errorf("unexpected PanicError: %v (stack %d bytes)", pe, len(pe.Stack))
+	}
+	if newPanicError("str").Unwrap() != nil {
+		t.Error("non-error panic value should not unwrap")
+	}
+}
+
+func TestWithoutPanicRecoveryPropagates(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic without WithPanicRecovery")
+		}
+	}()
+	PipeMap(FromSlice([]int{1, 2}), func(n int) int { panic("bad") }).Collect()
+}
+
+func TestPanicRecoveryPipeMap(t *testing.T) {
+	var hooked atomic.Int64
+	p := PipeMap(
+		FromSlice([]int{1, 2, 3, 4}).
+			WithPanicRecovery().
+			WithErrorHook(CountErrors[int](&hooked)),
+		func(n int) int {
+			if n == 2 {
+				panic("two")
+			}
+			return n * 10
+		},
+	)
+	assertSliceEqual(t, []int{10, 30, 40}, p.Collect())
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) || pe.Value != "two" {
+		t.Errorf("expected PanicError(two), got %v", p.Err())
+	}
+	if hooked.Load() != 1 {
+		t.Errorf("expected 1 error hook call, got %d", hooked.Load())
+	}
+}
+
+func TestPanicRecoveryFilterAbort(t *testing.T) {
+	p := FromSlice([]int{1, 2, 3, 4}).
+		WithPanicRecovery().
+		WithErrorHandler(AbortOnError[int]()).
+		Filter(func(n int) bool {
+			if n == 3 {
+				panic("three")
+			}
+			return true
+		})
+	assertSliceEqual(t, []int{1, 2}, p.Collect())
+	if p.Err() == nil {
+		t.Error("expected Err after recovered panic")
+	}
+}
+
+func TestPanicRecoveryRetry(t *testing.T) {
+	calls := 0
+	p := PipeMap(
+		FromSlice([]int{1}).
+			WithPanicRecovery().
+			WithErrorHandler(RetryHandler[int](3, 0)),
+		func(n int) int {
+			calls++
+			if calls < 2 {
+				panic("flaky")
+			}
+			return n
+		},
+	)
+	assertSliceEqual(t, []int{1}, p.Collect())
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	if p.Err() != nil {
+		t.Errorf("successful retry should not set Err, got %v", p.Err())
+	}
+}
+
+func TestPanicRecoveryPipeMapErr(t *testing.T) {
+	p := PipeMapErr(FromSlice([]int{1, 2, 3}).WithPanicRecovery(), func(n int) (int, error) {
+		if n == 1 {
+			panic(errors.New("one"))
+		}
+		return n, nil
+	})
+	assertSliceEqual(t, []int{2, 3}, p.Collect())
+	if p.Err() == nil || !strings.Contains(p.Err().Error(), "one") {
+		t.Errorf("expected recovered panic, got %v", p.Err())
+	}
+}
+
+func TestPanicRecoveryPipeMapErrRetry(t *testing.T) {
+	calls := 0
+	p := PipeMapErr(
+		FromSlice([]int{1}).
+			WithPanicRecovery().
+			WithErrorHandler(RetryHandler[int](3, 0)),
+		func(n int) (int, error) {
+			calls++
+			if calls < 2 {
+				panic("flaky")
+			}
+			return n, nil
+		},
+	)
+	assertSliceEqual(t, []int{1}, p.Collect())
+	if p.Err() != nil {
+		t.Errorf("successful retry should not set Err, got %v", p.Err())
+	}
+
+	// A panic on the last attempt is reported.
+	p = PipeMapErr(
+		FromSlice([]int{1, 2}).
+			WithPanicRecovery().
+			WithErrorHandler(RetryHandler[int](2, 0)),
+		func(n int) (int, error) {
+			if n == 1 {
+				panic("always")
+			}
+			return n, nil
+		},
+	)
+	assertSliceEqual(t, []int{2}, p.Collect())
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) {
+		t.Errorf("expected PanicError, got %v", p.Err())
+	}
+}
+
+func TestPanicRecoveryFlatMapPropagatesDownstream(t *testing.T) {
+	src := FromSlice([]int{1, 2, 3}).WithPanicRecovery()
+	doubled := PipeFlatMap(src, func(n int) []int { return []int{n, n} })
+	p := PipeMap(doubled, func(n int) int {
+		if n == 2 {
+			panic("two")
+		}
+		return n
+	}).Take(10)
+	assertSliceEqual(t, []int{1, 1, 3, 3}, p.Collect())
+	if p.Err() == nil {
+		t.Error("expected Err to surface through Take")
+	}
+}
+
+func TestPanicRecoveryParallel(t *testing.T) {
+	var hooked atomic.Int64
+	src := FromSlice([]int{1, 2, 3, 4, 5, 6}).WithErrorHook(CountErrors[int](&hooked))
+	p := PipeMapParallel(src, 3, func(n int) int {
+		if n%2 == 0 {
+			panic(n)
+		}
+		return n
+	})
+	assertSliceEqual(t, []int{1, 3, 5}, p.Collect())
+	var pe *PanicError
+	if !errors.As(p.Err(), &pe) || pe.Value != 2 {
+		t.Errorf("expected first panic (2), got %v", p.Err())
+	}
+	if hooked.Load() != 3 {
+		t.Errorf("expected 3 error hook calls, got %d", hooked.Load())
+	}
+}
+
+func TestPanicRecoveryParallelAbort(t *testing.T) {
+	src := FromSlice([]int{1, 2, 3, 4}).WithErrorHandler(AbortOnError[int]())
+	p := PipeFilterParallel(src, 2, func(n int) bool {
+		if n == 3 {
+			panic("three")
+		}
+		return true
+	})
+	assertSliceEqual(t, []int{1, 2}, p.Collect())
+}
+
+func TestPanicRecoveryParallelErr(t *testing.T) {
+	p := PipeMapParallelErr(FromSlice([]int{1, 2, 3}), 2, func(n int) (int, error) {
+		if n == 2 {
+			panic("two")
+		}
+		return n, nil
+	})
+	assertSliceEqual(t, []int{1, 3}, p.Collect())
+	if p.Err() == nil {
+		t.Error("expected Err")
+	}
+}
+
+func TestPanicRecoveryParallelStream(t *testing.T) {
+	p := PipeMapParallelStream(FromRange(0, 20), 4, 2, func(n int) int {
+		if n == 7 {
+			panic("seven")
+		}
+		return n
+	})
+	got := p.Collect()
+	if len(got) != 19 {
+		t.Fatalf("expected 19 results, got %v", got)
+	}
+	for i, v := range got {
+		want := i
+		if i >= 7 {
+			want = i + 1
+		}
+		if v != want {
+			t.Fatalf("order broken at %d: %v", i, got)
+		}
+	}
+	if p.Err() == nil {
+		t.Error("expected Err")
+	}
+}
+
+func TestPanicRecoveryParallelStreamAbortDeliversBuffered(t *testing.T) {
+	aborted := make(chan struct{})
+	src := FromRange(0, 1000).WithErrorHandler(func(error, int, int) ErrorAction {
+		close(aborted)
+		return Abort
+	})
+	p := PipeMapParallelStream(src, 4, 10, func(n int) int {
+		if n == 5 {
+			panic("five")
+		}
+		return n
+	})
+	// 0-4 are buffered in the stage when the abort happens.
+	<-aborted
+	assertSliceEqual(t, []int{0, 1, 2, 3, 4}, p.Collect())
+	if p.Err() == nil {
+		t.Error("expected Err")
+	}
+}
+
+func TestPanicRecoveryParallelStreamAbort(t *testing.T) {
+	src := FromRange(0, 1000).WithErrorHandler(AbortOnError[int]())
+	p := PipeMapParallelStream(src, 4, 2, func(n int) int {
+		if n == 5 {
+			panic("five")
+		}
+		time.Sleep(time.Millisecond)
+		return n
+	})
+	// Everything before the aborted element is delivered, in order.
+	assertSliceEqual(t, []int{0, 1, 2, 3, 4}, p.Collect())
+	if p.Err() == nil {
+		t.Error("expected Err")
+	}
+}
+
+func TestPanicRecoveryParallelStreamUpstreamFilter(t *testing.T) {
+	src := FromRange(0, 10).Filter(func(n int) bool {
+		if n == 4 {
+			panic("filter")
+		}
+		return true
+	})
+	p := PipeMapParallelStream(src, 2, 2, func(n int) int { return n })
+	assertSliceEqual(t, []int{0, 1, 2, 3}, p.Collect())
+	if p.Err() == nil {
+		t.Error("expected Err")
+	}
+}
+
+func TestPanicRecoveryBatchTimeoutReader(t *testing.T) {
+	src := FromRange(0, 10).Filter(func(n int) bool {
+		if n == 5 {
+			panic("five")
+		}
+		return true
+	})
+	p := PipeBatch(src, BatchConfig{Size: 2, MaxWait: 50 * time.Millisecond})
+	got := p.Collect()
+	total := 0
+	for _, b := range got {
+		total += len(b)
+	}
+	if total != 5 {
+		t.Errorf("expected 5 elements before panic, got %v", got)
+	}
+	if p.Err() == nil {
+		t.Error("expected Err")
+	}
+}
@@ -2,18 +2,24 @@ package gosplice
 
 import (
 	"context"
-	"runtime"
+	"slices"
 	"sync"
 )
 
-type indexed[U any] struct {
-	i int
-	v U
+type indexed[T any, U any] struct {
+	i    int
+	v    U
+	item T
+	perr *PanicError
 }
 
 func parallelResult[T any, U any](p *Pipeline[T], data []U, cancelled bool) *Pipeline[U] {
 	r := FromSlice(data)
+	r.hooks.RecoverPanics = p.hooks.RecoverPanics
+	// The upstream is fully drained (or cancelled) — release it now.
+	r.setErr(closeSource(p.source))
 	r.cancel = p.cancel
+	r.stop = p.stop
 	if cancelled {
 		r.setErr(p.ctx.Err())
 	} else {
@@ -25,6 +31,10 @@ func parallelResult[T any, U any](p *Pipeline[T], data []U, cancelled bool) *Pip
 
 // PipeMapParallel drains the source into memory first, then splits across workers.
 // Order is preserved. For unbounded sources use PipeMapParallelStream.
+//
+// A panic in fn is recovered per element: the element is dropped, the
+// *PanicError goes through the error handler (Abort truncates the output at
+// that element) and the first one is reported by Err() on the result.
 func PipeMapParallel[T any, U any](p *Pipeline[T], workers int, fn func(T) U) *Pipeline[U] {
 	items, cancelled := drainSourceCtx(p.source, p.ctx)
 	n := len(items)
@@ -33,30 +43,20 @@ func PipeMapParallel[T any, U any](p *Pipeline[T], workers int, fn func(T) U) *P
 	}
 
 	results := make([]U, n)
-	var wg sync.WaitGroup
-	batchSize := (n + workers - 1) / workers
-
-	for w := 0; w < workers; w++ {
-		lo := w * batchSize
-		hi := lo + batchSize
-		if hi > n {
-			hi = n
-		}
-		if lo >= n {
-			break
-		}
-		wg.Add(1)
-		go func(lo, hi int) {
-			defer wg.Done()
-			for i := lo; i < hi; i++ {
-				results[i] = fn(items[i])
-			}
-		}(lo, hi)
+	panics := runParallel(n, workers, func(i int) {
+		results[i] = fn(items[i])
+	})
+	if len(panics) == 0 {
+		return parallelResult[T, U](p, results, cancelled)
 	}
-	wg.Wait()
-	return parallelResult[T, U](p, results, cancelled)
+	limit := handlePanics(p.hooks, items, panics)
+	r := parallelResult[T, U](p, dropPanicked(results, panics, limit), cancelled)
+	r.setErr(panics[0].err)
+	return r
 }
 
+// PipeFilterParallel evaluates fn across workers and keeps matching elements in order.
+// Panics in fn are handled as in PipeMapParallel.
 func PipeFilterParallel[T any](p *Pipeline[T], workers int, fn func(T) bool) *Pipeline[T] {
 	items, cancelled := drainSourceCtx(p.source, p.ctx)
 	n := len(items)
@@ -65,44 +65,44 @@ func PipeFilterParallel[T any](p *Pipeline[T], workers int, fn func(T) bool) *Pi
 	}
 
 	keep := make([]bool, n)
-	var wg sync.WaitGroup
-	batchSize := (n + workers - 1) / workers
-
-	for w := 0; w < workers; w++ {
-		lo := w * batchSize
-		hi := lo + batchSize
-		if hi > n {
-			hi = n
-		}
-		if lo >= n {
-			break
-		}
-		wg.Add(1)
-		go func(lo, hi int) {
-			defer wg.Done()
-			for i := lo; i < hi; i++ {
-				keep[i] = fn(items[i])
-			}
-		}(lo, hi)
+	panics := runParallel(n, workers, func(i int) {
+		keep[i] = fn(items[i])
+	})
+	limit := n
+	if len(panics) > 0 {
+		limit = handlePanics(p.hooks, items, panics)
 	}
-	wg.Wait()
 
 	count := 0
-	for _, k := range keep {
+	for _, k := range keep[:limit] {
 		if k {
 			count++
 		}
 	}
 	result := make([]T, 0, count)
-	for i, v := range items {
+	for i, v := range items[:limit] {
 		if keep[i] {
 			result = append(result, v)
 		}
 	}
-	return parallelResult[T, T](p, result, cancelled)
+	r := parallelResult[T, T](p, result, cancelled)
+	if len(panics) > 0 {
+		r.setErr(panics[0].err)
+	}
+	return r
 }
 
+// PipeMapParallelErr is PipeMapParallel for fallible functions. Errors go
+// through the error handler (retries are not supported) and the element is
+// skipped. Panics in fn are handled as in PipeMapParallel.
 func PipeMapParallelErr[T any, U any](p *Pipeline[T], workers int, fn func(T) (U, error)) *Pipeline[U] {
+	return mapParallelErr(p, workers, fn, nil)
+}
+
+// mapParallelErr implements PipeMapParallelErr. With a ctx, errors after
+// ctx is done are not reported: the output ends there and the result
+// carries ctx.Err().
+func mapParallelErr[T any, U any](p *Pipeline[T], workers int, fn func(T) (U, error), ctx context.Context) *Pipeline[U] {
 	items, cancelled := drainSourceCtx(p.source, p.ctx)
 	n := len(items)
 	if n == 0 {
@@ -111,7 +111,50 @@ func PipeMapParallelErr[T any, U any](p *Pipeline[T], workers int, fn func(T) (U
 
 	vals := make([]U, n)
 	errs := make([]error, n)
-	var wg sync.WaitGroup
+	panics := runParallel(n, workers, func(i int) {
+		vals[i], errs[i] = fn(items[i])
+	})
+	for _, pa := range panics {
+		errs[pa.i] = pa.err
+	}
+
+	out := make([]U, 0, n)
+	for i := range items {
+		if errs[i] != nil {
+			if ctx != nil && ctx.Err() != nil {
+				cancelled = true
+				break
+			}
+			action := p.hooks.handleError(errs[i], items[i], 1)
+			if _, isPanic := errs[i].(*PanicError); isPanic && action == Abort {
+				break
+			}
+			continue
+		}
+		out = append(out, vals[i])
+	}
+	r := parallelResult[T, U](p, out, cancelled)
+	if len(panics) > 0 {
+		r.setErr(panics[0].err)
+	}
+	return r
+}
+
+type panicAt struct {
+	i   int
+	err *PanicError
+}
+
+// runParallel splits [0, n) into contiguous ranges across workers and calls
+// fn(i) for every index. A panic is recovered per element and the worker
+// moves on to the next index, so one bad element cannot crash the process.
+// The returned panics are sorted by index.
+func runParallel(n, workers int, fn func(i int)) []panicAt {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		panics []panicAt
+	)
 	batchSize := (n + workers - 1) / workers
 
 	for w := 0; w < workers; w++ {
@@ -126,33 +169,116 @@ func PipeMapParallelErr[T any, U any](p *Pipeline[T], workers int, fn func(T) (U
 		wg.Add(1)
 		go func(lo, hi int) {
 			defer wg.Done()
-			for i := lo; i < hi; i++ {
-				vals[i], errs[i] = fn(items[i])
+			for lo < hi {
+				i, perr := runRange(lo, hi, fn)
+				if perr == nil {
+					return
+				}
+				mu.Lock()
+				panics = append(panics, panicAt{i: i, err: perr})
+				mu.Unlock()
+				lo = i + 1
 			}
 		}(lo, hi)
 	}
 	wg.Wait()
+	slices.SortFunc(panics, func(a, b panicAt) int { return a.i - b.i })
+	return panics
+}
 
-	out := make([]U, 0, n)
-	for i := range items {
-		if errs[i] != nil {
-			p.hooks.handleError(errs[i], items[i], 1)
-			continue
+// runRange calls fn for [lo, hi) and stops at the first panic, returning its index.
+func runRange(lo, hi int, fn func(int)) (i int, perr *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			perr = newPanicError(r)
 		}
-		out = append(out, vals[i])
+	}()
+	for i = lo; i < hi; i++ {
+		fn(i)
+	}
+	return i, nil
+}
+
+// handlePanics routes recovered panics through the error handler in element
+// order. Returns the output length limit: the index of the first element
+// whose handler returned Abort, or len(items).
+func handlePanics[T any](h *Hooks[T], items []T, panics []panicAt) int {
+	for _, pa := range panics {
+		if h.handleError(pa.err, items[pa.i], 1) == Abort {
+			return pa.i
+		}
+	}
+	return len(items)
+}
+
+// dropPanicked returns vals[:limit] without the elements whose fn panicked.
+func dropPanicked[U any](vals []U, panics []panicAt, limit int) []U {
+	out := make([]U, 0, limit)
+	prev := 0
+	for _, pa := range panics {
+		if pa.i >= limit {
+			break
+		}
+		out = append(out, vals[prev:pa.i]...)
+		prev = pa.i + 1
 	}
-	return parallelResult[T, U](p, out, cancelled)
+	if prev < limit {
+		out = append(out, vals[prev:limit]...)
+	}
+	return out
 }
 
 // PipeMapParallelStream reads from the source incrementally with bounded buffer memory.
 // Order is preserved. Use for channels, readers, or large/infinite sources.
+//
+// A panic in fn is recovered in the worker and handled in element order as in
+// PipeMapParallel; Abort stops the stream and shuts down the workers. A panic
+// in an upstream stage (e.g. a Filter predicate, which runs on the dispatch
+// goroutine) ends the stream. Either way the first panic is reported by Err().
 func PipeMapParallelStream[T any, U any](p *Pipeline[T], workers int, bufSize int, fn func(T) U) *Pipeline[U] {
+	return PipeMapParallelStreamWith(p, StreamConfig{Workers: workers, Buffer: bufSize}, fn)
+}
+
+// StreamConfig configures PipeMapParallelStreamWith.
+type StreamConfig struct {
+	// Workers is the number of concurrent calls to fn.
+	Workers int
+
+	// Buffer is the capacity of the output buffer and, when shedding, of
+	// the queue of elements waiting for a worker.
+	Buffer int
+
+	// Shed selects what happens when every worker is busy and the queue is
+	// full. ShedBlock (the default) stops reading the source until a worker
+	// frees up; the other policies keep reading at full speed and drop
+	// elements (see ShedPolicy).
+	Shed ShedPolicy
+}
+
+// PipeMapParallelStreamWith is PipeMapParallelStream with a StreamConfig.
+// With a shedding policy the source is read on its own goroutine and never
+// waits for workers: elements that find every worker busy and the queue
+// full are shed, so latency stays bounded when the source outpaces fn.
+//
+//	feed = feed.WithShedHook(func(q Quote) { dropped.Add(1) })
+//	priced := gs.PipeMapParallelStreamWith(feed,
+//	    gs.StreamConfig{Workers: 4, Buffer: 16, Shed: gs.ShedDropOldest}, price)
+func PipeMapParallelStreamWith[T any, U any](p *Pipeline[T], cfg StreamConfig, fn func(T) U) *Pipeline[U] {
+	return mapParallelStream(p, cfg, func(_ context.Context, v T) U { return fn(v) })
+}
+
+// mapParallelStream implements the streaming parallel stages. fn receives
+// the stage's context, which is cancelled when the pipeline's context is
+// done or the consumer stops early.
+func mapParallelStream[T any, U any](p *Pipeline[T], cfg StreamConfig, fn func(context.Context, T) U) *Pipeline[U] {
+	workers, bufSize := max(cfg.Workers, 1), max(cfg.Buffer, 0)
 	src := p.source
 	hooks := p.hooks
 	fireHooks := hooks.hasElement()
 	pipeCtx := p.ctx
 	outCh := make(chan U, bufSize)
 	done := make(chan struct{})
+	errs := &errSlot{}
 
 	var mergedCtx context.Context
 	var mergedCancel context.CancelFunc
@@ -161,11 +287,16 @@ func PipeMapParallelStream[T any, U any](p *Pipeline[T], workers int, bufSize in
 	} else {
 		mergedCtx, mergedCancel = context.WithCancel(context.Background())
 	}
+	// workCtx stops the dispatcher and workers. An Abort cancels only it,
+	// so results already sent to outCh are still delivered.
+	workCtx, workCancel := context.WithCancel(mergedCtx)
+	call := func(v T) U { return fn(workCtx, v) }
 
 	go func() {
+		defer workCancel()
 		var wg sync.WaitGroup
 		sem := make(chan struct{}, workers)
-		resultCh := make(chan indexed[U], workers)
+		resultCh := make(chan indexed[T, U], workers)
 
 		// Watcher: when downstream closes done, cancel mergedCtx.
 		go func() {
@@ -179,82 +310,104 @@ func PipeMapParallelStream[T any, U any](p *Pipeline[T], workers int, bufSize in
 		senderDone := make(chan struct{})
 		go func() {
 			defer close(senderDone)
-			pending := make(map[int]U)
+			pending := make(map[int]indexed[T, U])
 			nextOut := 0
 
+			// emit sends ready results in order, routing recovered panics
+			// through the error handler. Returns false when the stream stops.
+			emit := func() bool {
+				for {
+					ir, exists := pending[nextOut]
+					if !exists {
+						return true
+					}
+					delete(pending, nextOut)
+					nextOut++
+					if ir.perr != nil {
+						errs.set(ir.perr)
+						if hooks.handleError(ir.perr, ir.item, 1) == Abort {
+							workCancel()
+							return false
+						}
+						continue
+					}
+					select {
+					case outCh <- ir.v:
+					case <-workCtx.Done():
+						return false
+					}
+				}
+			}
+
 			for {
 				select {
 				case ir, ok := <-resultCh:
 					if !ok {
 						// resultCh closed — flush remaining pending in order.
-						for {
-							v, exists := pending[nextOut]
-							if !exists {
-								return
-							}
-							delete(pending, nextOut)
-							nextOut++
-							select {
-							case outCh <- v:
-							case <-mergedCtx.Done():
-								return
-							}
-						}
+						emit()
+						return
 					}
-					pending[ir.i] = ir.v
-					for {
-						v, exists := pending[nextOut]
-						if !exists {
-							break
-						}
-						delete(pending, nextOut)
-						nextOut++
-						select {
-						case outCh <- v:
-						case <-mergedCtx.Done():
-							return
-						}
+					pending[ir.i] = ir
+					if !emit() {
+						return
 					}
-				case <-mergedCtx.Done():
+				case <-workCtx.Done():
 					return
 				}
 			}
 		}()
 
-		// Dispatch: read from source, spawn workers.
-		idx := 0
-	dispatch:
-		for {
-			select {
-			case <-mergedCtx.Done():
-				break dispatch
-			default:
-			}
-
-			v, ok := src.Next()
-			if !ok {
-				break dispatch
-			}
-			if fireHooks {
-				hooks.fireElement(v)
-			}
-			myIdx := idx
-			idx++
-
-			select {
-			case sem <- struct{}{}:
-			case <-mergedCtx.Done():
-				break dispatch
-			}
+		// spawn runs fn on item in a worker; the caller holds a sem slot.
+		spawn := func(item T, i int) {
 			wg.Add(1)
-			go func(item T, i int) {
+			go func() {
 				defer func() { <-sem; wg.Done() }()
-				result := fn(item)
+				result, perr := callRecover(call, item)
+				ir := indexed[T, U]{i: i, v: result}
+				if perr != nil {
+					ir.item, ir.perr = item, perr
+				}
 				select {
-				case resultCh <- indexed[U]{i: i, v: result}:
-				case <-mergedCtx.Done():
+				case resultCh <- ir:
+				case <-workCtx.Done():
 				}
-			}(v, myIdx)
+			}()
+		}
+
+		if cfg.Shed != ShedBlock {
+			dispatchShedding(src, hooks, cfg.Shed, max(bufSize, 1), sem, spawn, errs, workCtx, workCancel)
+		} else {
+			// Dispatch: read from source, spawn workers.
+			idx := 0
+		dispatch:
+			for {
+				select {
+				case <-workCtx.Done():
+					break dispatch
+				default:
+				}
+
+				v, ok, perr := nextRecover(src)
+				if perr != nil {
+					errs.set(perr)
+					break dispatch
+				}
+				if !ok {
+					errs.set(innerErr(src))
+					break dispatch
+				}
+				if fireHooks {
+					hooks.fireElement(v)
+				}
+
+				select {
+				case sem <- struct{}{}:
+				case <-workCtx.Done():
+					break dispatch
+				}
+				spawn(v, idx)
+				idx++
+			}
 		}
 
 		wg.Wait()
@@ -263,11 +416,13 @@ func PipeMapParallelStream[T any, U any](p *Pipeline[T], workers int, bufSize in
 		close(outCh)
 	}()
 
-	ss := &stoppableSource[U]{ch: outCh, done: done, ctx: mergedCtx, cancelFn: mergedCancel}
-	runtime.SetFinalizer(ss, (*stoppableSource[U]).stop)
+	ss := &stoppableSource[U]{ch: outCh, done: done, ctx: mergedCtx, cancelFn: mergedCancel, errs: errs, inner: src}
 	r := newPipeline[U](ss)
+	r.hooks.RecoverPanics = p.hooks.RecoverPanics
 	r.ctx = p.ctx
 
+	r.stop = p.stop
+
 	pCancel := p.cancel
 	r.cancel = func() {
 		mergedCancel()
@@ -278,12 +433,107 @@ func PipeMapParallelStream[T any, U any](p *Pipeline[T], workers int, bufSize in
 	return r
 }
 
+// dispatchShedding reads the source on its own goroutine so it is never
+// blocked by busy workers, queues up to capacity elements for them and
+// sheds the rest under policy. Returns when the source is exhausted and the
+// queue drained, or when ctx is done — in both cases only after the reader
+// has exited, so it never outlives the stage's output channel.
+func dispatchShedding[T any](src Source[T], hooks *Hooks[T], policy ShedPolicy, capacity int,
+	sem chan struct{}, spawn func(T, int), errs *errSlot, ctx context.Context, cancel context.CancelFunc) {
+	itemCh := make(chan T)
+	go func() {
+		defer close(itemCh)
+		for {
+			v, ok, perr := nextRecover(src)
+			if perr != nil {
+				errs.set(perr)
+				return
+			}
+			if !ok {
+				errs.set(innerErr(src))
+				return
+			}
+			hooks.fireElement(v)
+			select {
+			case itemCh <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	// On an early return the reader may be parked in Next; wait for it.
+	defer func() {
+		for range itemCh {
+		}
+	}()
+
+	queue := make([]T, 0, capacity)
+	in := itemCh
+	idx := 0
+	for in != nil || len(queue) > 0 {
+		// Offer a worker slot only when something is queued.
+		var slot chan struct{}
+		if len(queue) > 0 {
+			slot = sem
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case v, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			if len(queue) < capacity {
+				queue = append(queue, v)
+				continue
+			}
+			if policy == ShedDropOldest {
+				oldest := queue[0]
+				copy(queue, queue[1:])
+				queue[len(queue)-1] = v
+				v = oldest
+			}
+			if shedOne(hooks, policy, v, errs) {
+				cancel()
+				return
+			}
+		case slot <- struct{}{}:
+			spawn(queue[0], idx)
+			idx++
+			var zero T
+			queue[0] = zero
+			queue = queue[1:]
+		}
+	}
+}
+
+// stoppableSource yields results from the stream's sender. Err reports a
+// recovered panic, or else the upstream source's error, which the
+// dispatcher records once the source is exhausted so Err never reads the
+// source concurrently with it.
 type stoppableSource[T any] struct {
 	ch       <-chan T
 	done     chan struct{}
 	ctx      context.Context
 	cancelFn context.CancelFunc
 	once     sync.Once
+	errs     *errSlot
+	inner    any
+}
+
+// Close stops the dispatcher and workers and closes the upstream source,
+// unblocking a dispatcher parked in Next on an owned reader.
+func (s *stoppableSource[T]) Close() error {
+	s.stop()
+	return closeSource(s.inner)
+}
+
+func (s *stoppableSource[T]) Err() error {
+	if s.errs == nil {
+		return nil
+	}
+	return s.errs.get()
 }
 
 func (s *stoppableSource[T]) Next() (T, bool) {
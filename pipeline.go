@@ -26,8 +26,11 @@ type Pipeline[T any] struct {
 	hooks        *Hooks[T]
 	ctx          context.Context
 	cancel       context.CancelFunc
+	stop         func() // stops upstream stage goroutines; unlike cancel, WithContext keeps it
 	pErr         atomic.Pointer[error]
 	finalizeOnce sync.Once
+	closeOnce    sync.Once
+	closeErr     error
 	ctxNoop      bool // true when ctx != nil but uncancelable (Background/TODO)
 }
 
@@ -88,11 +91,28 @@ func (p *Pipeline[T]) WithMaxRetries(n int) *Pipeline[T] {
 	return p
 }
 
+// WithElementTimeout gives every call of a ctx stage reading from p
+// (PipeMapCtx, PipeFilterCtx, PipeFlatMapCtx and the parallel Ctx
+// variants) its own context with deadline d, derived from the pipeline's.
+// A call that overruns fails with context.DeadlineExceeded, which goes
+// through the error handler like any other error.
+func (p *Pipeline[T]) WithElementTimeout(d time.Duration) *Pipeline[T] {
+	p.hooks.ElementTimeout = d
+	return p
+}
+
 func (p *Pipeline[T]) WithBatchHook(fn BatchHook[T]) *Pipeline[T] {
 	p.hooks.OnBatch = append(p.hooks.OnBatch, fn)
 	return p
 }
 
+// WithShedHook registers fn for elements dropped by a load-shedding stage
+// (see ShedPolicy).
+func (p *Pipeline[T]) WithShedHook(fn ShedHook[T]) *Pipeline[T] {
+	p.hooks.OnShed = append(p.hooks.OnShed, fn)
+	return p
+}
+
 func (p *Pipeline[T]) WithCompletionHook(fn CompletionHook) *Pipeline[T] {
 	p.hooks.OnCompletion = append(p.hooks.OnCompletion, fn)
 	return p
@@ -120,10 +140,11 @@ func (p *Pipeline[T]) WithTimeout(d time.Duration) *Pipeline[T] {
 
 func (p *Pipeline[T]) Filter(fn func(T) bool) *Pipeline[T] {
 	return &Pipeline[T]{
-		source:  &filterSource[T]{inner: p.source, pred: fn},
+		source:  &filterSource[T]{inner: p.source, pred: fn, hooks: p.hooks},
 		hooks:   p.hooks,
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -134,6 +155,7 @@ func (p *Pipeline[T]) Take(n int) *Pipeline[T] {
 		hooks:   p.hooks,
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -144,6 +166,7 @@ func (p *Pipeline[T]) Skip(n int) *Pipeline[T] {
 		hooks:   p.hooks,
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -154,6 +177,7 @@ func (p *Pipeline[T]) Peek(fn func(T)) *Pipeline[T] {
 		hooks:   p.hooks,
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -169,6 +193,9 @@ func (p *Pipeline[T]) finalize() {
 		if p.cancel != nil {
 			p.cancel()
 		}
+		if p.stop != nil {
+			p.stop()
+		}
 		if p.Err() != nil && p.hooks.Timeout > 0 {
 			p.hooks.fireTimeout(p.hooks.Timeout)
 		}
@@ -176,6 +203,22 @@ func (p *Pipeline[T]) finalize() {
 	})
 }
 
+// Close releases everything the pipeline holds upstream: it stops the
+// background goroutines of PipeMapParallelStream and PipeBatch (with MaxWait),
+// closes sources that implement io.Closer (FromReadCloser, custom sources)
+// and finalizes the pipeline (cancel, completion hooks).
+//
+// Terminals finalize on their own, and Take, First and Any close upstream
+// when they stop early, so Close is only required when a pipeline is
+// abandoned mid-iteration or never run. Safe to call more than once.
+func (p *Pipeline[T]) Close() error {
+	p.closeOnce.Do(func() {
+		p.closeErr = closeSource(p.source)
+		p.finalize()
+	})
+	return p.closeErr
+}
+
 func (p *Pipeline[T]) Collect() []T {
 	defer p.finalize()
 
@@ -255,25 +298,36 @@ func (p *Pipeline[T]) First() (T, bool) {
 	if ok && p.hooks.hasElement() {
 		p.hooks.fireElement(v)
 	}
+	if ok {
+		p.setErr(p.Close())
+	}
 	return v, ok
 }
 
 func (p *Pipeline[T]) Any(pred func(T) bool) bool {
 	defer p.finalize()
-	return foldWhile(p, false, func(_ bool, v T) (bool, bool) {
+	found := foldWhile(p, false, func(_ bool, v T) (bool, bool) {
 		if pred(v) {
 			return true, false
 		}
 		return false, true
 	})
+	if found {
+		p.setErr(p.Close())
+	}
+	return found
 }
 
 func (p *Pipeline[T]) All(pred func(T) bool) bool {
 	defer p.finalize()
-	return !foldWhile(p, false, func(_ bool, v T) (bool, bool) {
+	failed := foldWhile(p, false, func(_ bool, v T) (bool, bool) {
 		if !pred(v) {
 			return true, false
 		}
 		return false, true
 	})
+	if failed {
+		p.setErr(p.Close())
+	}
+	return !failed
 }
@@ -0,0 +1,199 @@
+package gosplice
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// PivotAgg selects how a PivotTable reduces the values in each cell.
+type PivotAgg uint8
+
+const (
+	PivotSum PivotAgg = iota
+	PivotMean
+	PivotCount
+	PivotMin
+	PivotMax
+)
+
+func (a PivotAgg) String() string {
+	switch a {
+	case PivotSum:
+		return "sum"
+	case PivotMean:
+		return "mean"
+	case PivotCount:
+		return "count"
+	case PivotMin:
+		return "min"
+	case PivotMax:
+		return "max"
+	}
+	return "PivotAgg(" + strconv.Itoa(int(a)) + ")"
+}
+
+// PivotTable is a two-way aggregation built by Pivot or CrossTab. Rows and
+// Cols hold the labels in ascending order. Each cell, row, column and the
+// grand total keep their own accumulator, so margins aggregate the
+// underlying values (the mean margin is the mean of all values in the row,
+// not the mean of the cell means), like pandas' pivot_table(margins=True).
+type PivotTable[R, C cmp.Ordered] struct {
+	Rows []R
+	Cols []C
+	Agg  PivotAgg
+
+	cells     map[pivotCell[R, C]]*pivotAcc
+	rowTotals map[R]*pivotAcc
+	colTotals map[C]*pivotAcc
+	total     pivotAcc
+}
+
+// pivotAcc keeps a running sum plus the range; pivot tables need no
+// variance.
+type pivotAcc struct {
+	MeanAcc
+	Range MinMaxAcc
+}
+
+func (a *pivotAcc) Add(x float64) {
+	a.MeanAcc.Add(x)
+	a.Range.Add(x)
+}
+
+type pivotCell[R, C cmp.Ordered] struct {
+	r R
+	c C
+}
+
+// Pivot groups elements by rowKey and colKey and aggregates valueFn in each
+// cell with agg ("sum of sales by region and month"). Single pass; memory
+// is one accumulator per non-empty cell.
+func Pivot[T any, R, C cmp.Ordered](p *Pipeline[T], rowKey func(T) R, colKey func(T) C, valueFn func(T) float64, agg PivotAgg) *PivotTable[R, C] {
+	defer p.finalize()
+	t := &PivotTable[R, C]{
+		Agg:       agg,
+		cells:     make(map[pivotCell[R, C]]*pivotAcc),
+		rowTotals: make(map[R]*pivotAcc),
+		colTotals: make(map[C]*pivotAcc),
+	}
+	drain(p, func(v T) {
+		var x float64
+		if valueFn != nil {
+			x = valueFn(v)
+		}
+		t.add(rowKey(v), colKey(v), x)
+	})
+	t.Rows = slices.Sorted(maps.Keys(t.rowTotals))
+	t.Cols = slices.Sorted(maps.Keys(t.colTotals))
+	return t
+}
+
+// CrossTab counts elements by rowKey and colKey — a frequency table, like
+// pandas' crosstab.
+func CrossTab[T any, R, C cmp.Ordered](p *Pipeline[T], rowKey func(T) R, colKey func(T) C) *PivotTable[R, C] {
+	return Pivot(p, rowKey, colKey, nil, PivotCount)
+}
+
+func (t *PivotTable[R, C]) add(r R, c C, x float64) {
+	k := pivotCell[R, C]{r, c}
+	if t.cells[k] == nil {
+		t.cells[k] = new(pivotAcc)
+	}
+	if t.rowTotals[r] == nil {
+		t.rowTotals[r] = new(pivotAcc)
+	}
+	if t.colTotals[c] == nil {
+		t.colTotals[c] = new(pivotAcc)
+	}
+	t.cells[k].Add(x)
+	t.rowTotals[r].Add(x)
+	t.colTotals[c].Add(x)
+	t.total.Add(x)
+}
+
+// Get returns the aggregate of cell (r, c); ok is false when no element
+// fell into it.
+func (t *PivotTable[R, C]) Get(r R, c C) (v float64, ok bool) {
+	return t.result(t.cells[pivotCell[R, C]{r, c}])
+}
+
+// RowTotal returns the aggregate over every value in row r.
+func (t *PivotTable[R, C]) RowTotal(r R) (float64, bool) { return t.result(t.rowTotals[r]) }
+
+// ColTotal returns the aggregate over every value in column c.
+func (t *PivotTable[R, C]) ColTotal(c C) (float64, bool) { return t.result(t.colTotals[c]) }
+
+// Total returns the aggregate over all values.
+func (t *PivotTable[R, C]) Total() float64 {
+	v, _ := t.result(&t.total)
+	return v
+}
+
+func (t *PivotTable[R, C]) result(a *pivotAcc) (float64, bool) {
+	if a == nil || a.Count == 0 {
+		return 0, false
+	}
+	switch t.Agg {
+	case PivotMean:
+		return a.Result(), true
+	case PivotCount:
+		return float64(a.Count), true
+	case PivotMin:
+		return a.Range.Min, true
+	case PivotMax:
+		return a.Range.Max, true
+	}
+	return a.Sum, true
+}
+
+// WriteCSV writes the table through ToCSV: a header row of column labels
+// (the first cell is empty), then one row per row label. Empty cells are
+// written as "". With margins, a "Total" column and a "Total" row are
+// added. The header is always written, whatever cfg.Header says; labels
+// are formatted with fmt.Sprint.
+func (t *PivotTable[R, C]) WriteCSV(w io.Writer, cfg CSVConfig, margins bool) error {
+	header := make([]string, 0, len(t.Cols)+2)
+	header = append(header, "")
+	for _, c := range t.Cols {
+		header = append(header, fmt.Sprint(c))
+	}
+	if margins {
+		header = append(header, "Total")
+	}
+
+	lines := make([][]string, 0, len(t.Rows)+1)
+	for _, r := range t.Rows {
+		line := make([]string, 0, len(header))
+		line = append(line, fmt.Sprint(r))
+		for _, c := range t.Cols {
+			line = append(line, formatPivot(t.Get(r, c)))
+		}
+		if margins {
+			line = append(line, formatPivot(t.RowTotal(r)))
+		}
+		lines = append(lines, line)
+	}
+	if margins {
+		line := make([]string, 0, len(header))
+		line = append(line, "Total")
+		for _, c := range t.Cols {
+			line = append(line, formatPivot(t.ColTotal(c)))
+		}
+		line = append(line, formatPivot(t.result(&t.total)))
+		lines = append(lines, line)
+	}
+
+	cfg.Header = true
+	return ToCSV(FromSlice(lines), w, cfg, header, func(l []string) []string { return l })
+}
+
+func formatPivot(v float64, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,99 @@
+package gosplice
+
+import (
+	"strings"
+	"testing"
+)
+
+type sale struct {
+	Region string
+	Month  int
+	Amount float64
+}
+
+var sales = []sale{
+	{"west", 2, 10}, {"east", 1, 5}, {"west", 1, 20},
+	{"east", 1, 15}, {"west", 2, 30}, {"north", 3, 7},
+}
+
+func TestPivot(t *testing.T) {
+	region := func(s sale) string { return s.Region }
+	month := func(s sale) int { return s.Month }
+	amount := func(s sale) float64 { return s.Amount }
+
+	sum := Pivot(FromSlice(sales), region, month, amount, PivotSum)
+	if strings.Join(sum.Rows, ",") != "east,north,west" || len(sum.Cols) != 3 || sum.Cols[0] != 1 {
+		t.Fatalf("labels %v %v", sum.Rows, sum.Cols)
+	}
+	if v, ok := sum.Get("west", 2); !ok || v != 40 {
+		t.Errorf("west/2 = %v %v", v, ok)
+	}
+	if _, ok := sum.Get("east", 3); ok {
+		t.Error("empty cell reported as present")
+	}
+	if v, _ := sum.RowTotal("west"); v != 60 {
+		t.Errorf("west total = %v", v)
+	}
+	if v, _ := sum.ColTotal(1); v != 40 {
+		t.Errorf("month 1 total = %v", v)
+	}
+	if sum.Total() != 87 {
+		t.Errorf("total = %v", sum.Total())
+	}
+
+	// Margins aggregate the raw values: west's mean is 60/3, not the mean of 20 and 20.
+	mean := Pivot(FromSlice(sales), region, month, amount, PivotMean)
+	if v, _ := mean.Get("west", 2); v != 20 {
+		t.Errorf("mean west/2 = %v", v)
+	}
+	if v, _ := mean.RowTotal("west"); v != 20 {
+		t.Errorf("mean west = %v", v)
+	}
+	if !approxEqual(mean.Total(), 87.0/6, eps) {
+		t.Errorf("mean total = %v", mean.Total())
+	}
+
+	mn := Pivot(FromSlice(sales), region, month, amount, PivotMin)
+	mx := Pivot(FromSlice(sales), region, month, amount, PivotMax)
+	if v, _ := mn.ColTotal(1); v != 5 {
+		t.Errorf("min month 1 = %v", v)
+	}
+	if mx.Total() != 30 {
+		t.Errorf("max total = %v", mx.Total())
+	}
+}
+
+func TestCrossTab_WriteCSV(t *testing.T) {
+	ct := CrossTab(FromSlice(sales), func(s sale) string { return s.Region }, func(s sale) int { return s.Month })
+	if v, _ := ct.Get("west", 2); v != 2 || ct.Agg != PivotCount {
+		t.Errorf("west/2 = %v", v)
+	}
+
+	var b strings.Builder
+	if err := ct.WriteCSV(&b, CSVConfig{}, true); err != nil {
+		t.Fatal(err)
+	}
+	want := ",1,2,3,Total\n" +
+		"east,2,,,2\n" +
+		"north,,,1,1\n" +
+		"west,1,2,,3\n" +
+		"Total,3,2,1,6\n"
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := ct.WriteCSV(&b, CSVConfig{Comma: ';'}, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), ";1;2;3\neast;2;;\n") {
+		t.Errorf("without margins:\n%s", b.String())
+	}
+}
+
+func TestPivot_Empty(t *testing.T) {
+	pt := CrossTab(FromSlice([]sale{}), func(s sale) string { return s.Region }, func(s sale) int { return s.Month })
+	if len(pt.Rows) != 0 || len(pt.Cols) != 0 || pt.Total() != 0 {
+		t.Errorf("got %+v", pt)
+	}
+}
@@ -0,0 +1,332 @@
+package gosplice
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+)
+
+// DefaultCompression is the t-digest compression used by ApproxMedianBy,
+// ApproxPercentileBy and ApproxDescribeBy.
+const DefaultCompression = 100
+
+// QuantileSketch is a mergeable streaming quantile estimator (a merging
+// t-digest). Memory is bounded by the compression parameter, not by the
+// number of values: about 2×compression centroids are kept no matter how
+// many values are added.
+//
+// Compression trades memory for accuracy. Rank error is roughly
+// 1/compression around the median and much smaller towards the tails, so
+// p99/p999 stay accurate at the default of 100. Streams shorter than the
+// sketch capacity are answered exactly, with the same linear interpolation as
+// PercentileBy.
+//
+// Sketches from parallel workers or separate runs combine with Merge and
+// can be persisted with MarshalBinary/MarshalJSON. A QuantileSketch is not
+// safe for concurrent use; give each worker its own and merge at the end.
+type QuantileSketch struct {
+	compression float64
+	centroids   []centroid
+	buf         []centroid
+	total       float64
+	min, max    float64
+}
+
+type centroid struct {
+	mean, weight float64
+}
+
+// NewQuantileSketch returns an empty sketch. Compression ≤ 0 uses DefaultCompression.
+func NewQuantileSketch(compression float64) *QuantileSketch {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &QuantileSketch{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+func (s *QuantileSketch) bufferLimit() int { return int(5 * s.compression) }
+
+// Add inserts one value. NaN is ignored.
+func (s *QuantileSketch) Add(x float64) {
+	s.AddWeighted(x, 1)
+}
+
+// AddWeighted inserts x with the given weight (e.g. a pre-aggregated count).
+func (s *QuantileSketch) AddWeighted(x, w float64) {
+	if math.IsNaN(x) || w <= 0 {
+		return
+	}
+	s.buf = append(s.buf, centroid{mean: x, weight: w})
+	s.total += w
+	if x < s.min {
+		s.min = x
+	}
+	if x > s.max {
+		s.max = x
+	}
+	if len(s.buf) >= s.bufferLimit() {
+		s.compress()
+	}
+}
+
+// Merge folds other into s. other is not modified.
+func (s *QuantileSketch) Merge(other *QuantileSketch) {
+	if other == nil || other.total == 0 {
+		return
+	}
+	s.buf = append(s.buf, other.centroids...)
+	s.buf = append(s.buf, other.buf...)
+	s.total += other.total
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+	s.compress()
+}
+
+// Count returns the total weight added (the number of values for Add).
+func (s *QuantileSketch) Count() int { return int(s.total) }
+
+// Min returns the smallest value added, or 0 if empty.
+func (s *QuantileSketch) Min() float64 {
+	if s.total == 0 {
+		return 0
+	}
+	return s.min
+}
+
+// Max returns the largest value added, or 0 if empty.
+func (s *QuantileSketch) Max() float64 {
+	if s.total == 0 {
+		return 0
+	}
+	return s.max
+}
+
+// scale is the t-digest k1 scale function: centroids near q=0 and q=1 are
+// kept small, which is what makes tail quantiles accurate.
+func (s *QuantileSketch) scale(q float64) float64 {
+	return s.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// compress merges buffered points into the centroid list.
+func (s *QuantileSketch) compress() {
+	if len(s.buf) == 0 {
+		return
+	}
+	all := make([]centroid, 0, len(s.centroids)+len(s.buf))
+	all = append(all, s.centroids...)
+	all = append(all, s.buf...)
+	s.buf = s.buf[:0]
+	slices.SortFunc(all, func(a, b centroid) int {
+		switch {
+		case a.mean < b.mean:
+			return -1
+		case a.mean > b.mean:
+			return 1
+		}
+		return 0
+	})
+
+	out := make([]centroid, 0, len(s.centroids)+1)
+	cur := all[0]
+	soFar := 0.0
+	kLow := s.scale(0)
+	for _, c := range all[1:] {
+		q := (soFar + cur.weight + c.weight) / s.total
+		if s.scale(q)-kLow <= 1 {
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+		out = append(out, cur)
+		soFar += cur.weight
+		kLow = s.scale(soFar / s.total)
+		cur = c
+	}
+	s.centroids = append(out, cur)
+}
+
+// Quantile returns the estimated q-quantile, q in [0, 1]. Returns 0 if empty.
+func (s *QuantileSketch) Quantile(q float64) float64 {
+	if s.total == 0 {
+		return 0
+	}
+	s.compress()
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+	cs := s.centroids
+	if len(cs) == 1 {
+		return cs[0].mean
+	}
+
+	// Positions use the numpy convention (index = q × (n-1)); a centroid of
+	// weight w starting at cumulative weight c is centred at c + (w-1)/2.
+	target := q * (s.total - 1)
+	cum := 0.0
+	first := (cs[0].weight - 1) / 2
+	if target < first {
+		return s.min + (cs[0].mean-s.min)*target/first
+	}
+	for i := 0; i < len(cs)-1; i++ {
+		left := cum + (cs[i].weight-1)/2
+		right := cum + cs[i].weight + (cs[i+1].weight-1)/2
+		if target <= right {
+			frac := (target - left) / (right - left)
+			return cs[i].mean + frac*(cs[i+1].mean-cs[i].mean)
+		}
+		cum += cs[i].weight
+	}
+	last := cs[len(cs)-1]
+	lastPos := cum + (last.weight-1)/2
+	end := s.total - 1
+	if target <= lastPos || end == lastPos {
+		return last.mean
+	}
+	return last.mean + (s.max-last.mean)*(target-lastPos)/(end-lastPos)
+}
+
+// Percentile returns the estimated percentile, pct in [0, 100], matching PercentileBy.
+func (s *QuantileSketch) Percentile(pct float64) float64 {
+	return s.Quantile(pct / 100)
+}
+
+// Median returns the estimated median.
+func (s *QuantileSketch) Median() float64 { return s.Quantile(0.5) }
+
+// --- Serialisation ---
+
+const quantileSketchVersion = 1
+
+// MarshalBinary encodes the sketch (compressed first). Also used by encoding/gob.
+func (s *QuantileSketch) MarshalBinary() ([]byte, error) {
+	s.compress()
+	b := make([]byte, 0, 1+8*4+binary.MaxVarintLen64+16*len(s.centroids))
+	b = append(b, quantileSketchVersion)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(s.compression))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(s.total))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(s.min))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(s.max))
+	b = binary.AppendUvarint(b, uint64(len(s.centroids)))
+	for _, c := range s.centroids {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c.mean))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(c.weight))
+	}
+	return b, nil
+}
+
+var errSketchCorrupt = errors.New("gosplice: corrupt sketch encoding")
+
+// UnmarshalBinary restores a sketch written by MarshalBinary.
+func (s *QuantileSketch) UnmarshalBinary(b []byte) error {
+	if len(b) < 1+8*4 || b[0] != quantileSketchVersion {
+		return errSketchCorrupt
+	}
+	f := func(i int) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b[i:])) }
+	compression := f(1)
+	if !(compression > 0) || math.IsInf(compression, 1) {
+		return errSketchCorrupt
+	}
+	n, k := binary.Uvarint(b[33:])
+	if k <= 0 {
+		return errSketchCorrupt
+	}
+	// Compare n against the bytes left before multiplying, so a crafted
+	// count cannot overflow past the check.
+	rest := uint64(len(b) - 33 - k)
+	if n > rest/16 || rest != 16*n {
+		return errSketchCorrupt
+	}
+	off := 33 + k
+	centroids := make([]centroid, n)
+	for i := range centroids {
+		c := centroid{mean: f(off), weight: f(off + 8)}
+		if math.IsNaN(c.mean) || math.IsInf(c.mean, 0) || !(c.weight > 0) || math.IsInf(c.weight, 1) {
+			return errSketchCorrupt
+		}
+		centroids[i] = c
+		off += 16
+	}
+	s.compression, s.total, s.min, s.max = compression, f(9), f(17), f(25)
+	s.centroids, s.buf = centroids, nil
+	return nil
+}
+
+type quantileSketchJSON struct {
+	Compression float64      `json:"compression"`
+	Count       float64      `json:"count"`
+	Min         float64      `json:"min"`
+	Max         float64      `json:"max"`
+	Centroids   [][2]float64 `json:"centroids"`
+}
+
+// MarshalJSON encodes the sketch as {"compression", "count", "min", "max", "centroids": [[mean, weight]...]}.
+func (s *QuantileSketch) MarshalJSON() ([]byte, error) {
+	s.compress()
+	j := quantileSketchJSON{Compression: s.compression, Count: s.total, Centroids: make([][2]float64, len(s.centroids))}
+	if s.total > 0 {
+		j.Min, j.Max = s.min, s.max
+	}
+	for i, c := range s.centroids {
+		j.Centroids[i] = [2]float64{c.mean, c.weight}
+	}
+	return json.Marshal(j)
+}
+
+func (s *QuantileSketch) UnmarshalJSON(b []byte) error {
+	var j quantileSketchJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = *NewQuantileSketch(j.Compression)
+	if j.Count > 0 {
+		s.total, s.min, s.max = j.Count, j.Min, j.Max
+	}
+	s.centroids = make([]centroid, len(j.Centroids))
+	for i, c := range j.Centroids {
+		s.centroids[i] = centroid{mean: c[0], weight: c[1]}
+	}
+	return nil
+}
+
+// --- Pipeline aggregations ---
+
+// QuantileSketchBy builds a QuantileSketch over fn(v) in a single pass with
+// bounded memory. Use the sketch for several quantiles at once, or Merge it
+// with sketches from other workers or runs.
+func QuantileSketchBy[T any](p *Pipeline[T], compression float64, fn func(T) float64) *QuantileSketch {
+	defer p.finalize()
+	s := NewQuantileSketch(compression)
+	drain(p, func(v T) { s.Add(fn(v)) })
+	return s
+}
+
+// ApproxMedianBy is MedianBy with bounded memory (see QuantileSketch).
+func ApproxMedianBy[T any](p *Pipeline[T], fn func(T) float64) float64 {
+	return QuantileSketchBy(p, DefaultCompression, fn).Median()
+}
+
+// ApproxPercentileBy is PercentileBy with bounded memory (see QuantileSketch).
+func ApproxPercentileBy[T any](p *Pipeline[T], pct float64, fn func(T) float64) float64 {
+	return QuantileSketchBy(p, DefaultCompression, fn).Percentile(pct)
+}
+
+// ApproxDescribeBy is DescribeBy with bounded memory, built on StatsAcc:
+// count, sum, mean, stddev, min and max are exact (running sum, Welford);
+// Q1, median and Q3 come from a QuantileSketch. NaN is treated as in
+// DescribeBy: it counts, makes Sum, Mean and StdDev NaN and is ignored by
+// Min and Max. The sketch cannot rank NaN, so the quartiles are NaN too.
+func ApproxDescribeBy[T any](p *Pipeline[T], fn func(T) float64) Stats {
+	defer p.finalize()
+	var a StatsAcc
+	drain(p, func(v T) { a.Add(fn(v)) })
+	return a.Result()
+}
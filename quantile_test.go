@@ -0,0 +1,224 @@
+package gosplice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestQuantileSketch_ExactForSmallStreams(t *testing.T) {
+	data := []float64{7, 1, 9, 3, 5, 2, 8}
+	s := NewQuantileSketch(0)
+	for _, x := range data {
+		s.Add(x)
+	}
+	for _, pct := range []float64{0, 10, 25, 50, 75, 90, 100} {
+		want := Percentile(data, pct)
+		if got := s.Percentile(pct); !approxEqual(got, want, eps) {
+			t.Errorf("p%v: got %v, want %v", pct, got, want)
+		}
+	}
+}
+
+func TestQuantileSketch_Empty(t *testing.T) {
+	s := NewQuantileSketch(0)
+	if s.Median() != 0 || s.Min() != 0 || s.Max() != 0 || s.Count() != 0 {
+		t.Fatal("empty sketch should report zeros")
+	}
+}
+
+func TestQuantileSketch_LargeStreamAccuracy(t *testing.T) {
+	const n = 200_000
+	r := rand.New(rand.NewSource(1))
+	s := NewQuantileSketch(100)
+	for _, i := range r.Perm(n) {
+		s.Add(float64(i))
+	}
+	if len(s.centroids) > 300 {
+		t.Fatalf("sketch not bounded: %d centroids", len(s.centroids))
+	}
+	for _, tc := range []struct{ q, tol float64 }{
+		{0.5, 0.01}, {0.25, 0.01}, {0.9, 0.005}, {0.99, 0.001}, {0.999, 0.0005},
+	} {
+		got := s.Quantile(tc.q) / n
+		if math.Abs(got-tc.q) > tc.tol {
+			t.Errorf("q%v: rank %v, tolerance %v", tc.q, got, tc.tol)
+		}
+	}
+	if s.Min() != 0 || s.Max() != n-1 || s.Count() != n {
+		t.Fatalf("min/max/count = %v/%v/%v", s.Min(), s.Max(), s.Count())
+	}
+}
+
+func TestQuantileSketch_Merge(t *testing.T) {
+	a, b := NewQuantileSketch(0), NewQuantileSketch(0)
+	for i := 0; i < 50_000; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i + 50_000))
+	}
+	a.Merge(b)
+	if a.Count() != 100_000 || a.Min() != 0 || a.Max() != 99_999 {
+		t.Fatalf("merged count/min/max = %v/%v/%v", a.Count(), a.Min(), a.Max())
+	}
+	if got := a.Median(); math.Abs(got-50_000) > 1000 {
+		t.Errorf("merged median = %v", got)
+	}
+	if b.Count() != 50_000 {
+		t.Error("Merge must not modify its argument")
+	}
+}
+
+func TestQuantileSketch_Serialisation(t *testing.T) {
+	s := NewQuantileSketch(50)
+	for i := 0; i < 10_000; i++ {
+		s.Add(float64(i % 977))
+	}
+
+	bin, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBin QuantileSketch
+	if err := fromBin.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+
+	js, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON QuantileSketch
+	if err := json.Unmarshal(js, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		t.Fatal(err)
+	}
+	var fromGob QuantileSketch
+	if err := gob.NewDecoder(&buf).Decode(&fromGob); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, got := range []*QuantileSketch{&fromBin, &fromJSON, &fromGob} {
+		for _, q := range []float64{0, 0.1, 0.5, 0.99, 1} {
+			if !approxEqual(got.Quantile(q), s.Quantile(q), eps) {
+				t.Errorf("q%v: restored %v, original %v", q, got.Quantile(q), s.Quantile(q))
+			}
+		}
+		if got.Count() != s.Count() {
+			t.Errorf("count: restored %d, original %d", got.Count(), s.Count())
+		}
+	}
+
+	if err := fromBin.UnmarshalBinary(bin[:len(bin)-3]); err == nil {
+		t.Error("truncated encoding should fail")
+	}
+}
+
+func TestQuantileSketch_UnmarshalRejectsCrafted(t *testing.T) {
+	s := NewQuantileSketch(0)
+	s.Add(1)
+	valid, _ := s.MarshalBinary()
+	header := func(compression float64, n uint64) []byte {
+		b := append([]byte{}, valid[:33]...)
+		binary.LittleEndian.PutUint64(b[1:], math.Float64bits(compression))
+		return binary.AppendUvarint(b, n)
+	}
+	withCentroid := func(mean, weight float64) []byte {
+		b := header(DefaultCompression, 1)
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(mean))
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(weight))
+	}
+	cases := map[string][]byte{
+		// 16 * 2^60 overflows to 0, the number of bytes left.
+		"overflowing count":   header(DefaultCompression, 1<<60),
+		"zero compression":    header(0, 0),
+		"NaN compression":     header(math.NaN(), 0),
+		"NaN mean":            withCentroid(math.NaN(), 1),
+		"infinite mean":       withCentroid(math.Inf(-1), 1),
+		"infinite weight":     withCentroid(1, math.Inf(1)),
+		"non-positive weight": withCentroid(1, 0),
+	}
+	for name, b := range cases {
+		var got QuantileSketch
+		if err := got.UnmarshalBinary(b); err != errSketchCorrupt {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+	var got QuantileSketch
+	if err := got.UnmarshalBinary(withCentroid(1, 1)); err != nil {
+		t.Errorf("well-formed input: %v", err)
+	}
+}
+
+func TestApproxPercentileBy(t *testing.T) {
+	got := ApproxPercentileBy(FromRange(0, 100_001), 99, func(n int) float64 { return float64(n) })
+	if math.Abs(got-99_000) > 100 {
+		t.Errorf("p99 = %v", got)
+	}
+	if got := ApproxMedianBy(FromSlice([]int{3, 1, 2}), func(n int) float64 { return float64(n) }); got != 2 {
+		t.Errorf("median = %v", got)
+	}
+}
+
+func TestApproxDescribeBy_MatchesDescribeBy(t *testing.T) {
+	data := []float64{4, 8, 15, 16, 23, 42, 1, 7}
+	id := func(x float64) float64 { return x }
+	want := DescribeBy(FromSlice(data), id)
+	got := ApproxDescribeBy(FromSlice(data), id)
+	pairs := [][2]float64{
+		{got.Sum, want.Sum}, {got.Mean, want.Mean}, {got.StdDev, want.StdDev},
+		{got.Min, want.Min}, {got.Q1, want.Q1}, {got.Median, want.Median},
+		{got.Q3, want.Q3}, {got.Max, want.Max},
+	}
+	for i, p := range pairs {
+		if !approxEqual(p[0], p[1], eps) {
+			t.Errorf("field %d: got %v, want %v", i, p[0], p[1])
+		}
+	}
+	if got.Count != want.Count {
+		t.Errorf("count: got %d, want %d", got.Count, want.Count)
+	}
+	if (ApproxDescribeBy(FromSlice([]float64{}), id) != Stats{}) {
+		t.Error("empty input should give zero Stats")
+	}
+}
+
+func TestApproxDescribeBy_ExactSumAndNaN(t *testing.T) {
+	data := make([]float64, 10)
+	var sum float64
+	for i := range data {
+		data[i] = 0.1 * float64(i+1)
+		sum += data[i]
+	}
+	id := func(x float64) float64 { return x }
+	if got := ApproxDescribeBy(FromSlice(data), id).Sum; got != sum {
+		t.Errorf("sum: got %v, want %v", got, sum)
+	}
+
+	withNaN := []float64{3, math.NaN(), 1, 2}
+	got := ApproxDescribeBy(FromSlice(withNaN), id)
+	want := DescribeBy(FromSlice(withNaN), id)
+	if got.Count != want.Count || got.Min != want.Min || got.Max != want.Max {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	for i, x := range []float64{got.Sum, got.Mean, got.StdDev, got.Median} {
+		if !math.IsNaN(x) {
+			t.Errorf("field %d: got %v, want NaN", i, x)
+		}
+	}
+}
+
+func TestQuantileSketchBy_Compression(t *testing.T) {
+	coarse := QuantileSketchBy(FromRange(0, 100_000), 20, func(n int) float64 { return float64(n) })
+	fine := QuantileSketchBy(FromRange(0, 100_000), 500, func(n int) float64 { return float64(n) })
+	if len(coarse.centroids) >= len(fine.centroids) {
+		t.Errorf("compression 20 kept %d centroids, 500 kept %d", len(coarse.centroids), len(fine.centroids))
+	}
+}
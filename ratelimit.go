@@ -2,6 +2,7 @@ package gosplice
 
 import (
 	"context"
+	"math"
 	"sync"
 	"time"
 )
@@ -77,6 +78,7 @@ func (p *Pipeline[T]) RateLimit(cfg RateLimitConfig) *Pipeline[T] {
 		hooks:   p.hooks,
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -102,6 +104,10 @@ func (s *rateLimitSource[T]) SizeHint() int {
 	return -1
 }
 
+func (s *rateLimitSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *rateLimitSource[T]) Close() error { return closeSource(s.inner) }
+
 // ---------------------------------------------------------------------------
 // Context-aware variant
 // ---------------------------------------------------------------------------
@@ -121,6 +127,7 @@ func RateLimitCtx[T any](p *Pipeline[T], cfg RateLimitConfig, ctx context.Contex
 		hooks:   p.hooks,
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -146,6 +153,129 @@ func (s *rateLimitCtxSource[T]) SizeHint() int {
 	return -1
 }
 
+func (s *rateLimitCtxSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *rateLimitCtxSource[T]) Close() error { return closeSource(s.inner) }
+
+// ---------------------------------------------------------------------------
+// RateLimiter — shared handle
+// ---------------------------------------------------------------------------
+
+// RateLimiter is a token bucket that can be shared: pass one limiter to
+// RateLimitWith on several pipelines, or call Wait inside the function of
+// PipeMapParallelStream, and all of them draw from the same quota. Rate and
+// burst can be changed at runtime. Safe for concurrent use.
+//
+//	api := gs.NewRateLimiter(gs.RateLimitConfig{Rate: 100})
+//	users := gs.FromSlice(userIDs).RateLimitWith(api)
+//	orders := gs.FromSlice(orderIDs).RateLimitWith(api) // same 100/s in total
+type RateLimiter struct {
+	bucket   *tokenBucket
+	interval time.Duration
+
+	mu    sync.Mutex
+	rate  float64
+	burst float64
+}
+
+// NewRateLimiter creates a limiter from cfg, with a full bucket.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{
+		bucket:   &tokenBucket{lastTime: time.Now()},
+		interval: cfg.interval(),
+	}
+	l.set(float64(cfg.Rate), float64(cfg.burst()))
+	l.bucket.tokens = l.bucket.max
+	return l
+}
+
+// SetRate changes the sustained rate (elements per Interval). Tokens
+// already in the bucket are kept. Rates below a billionth of an element per
+// Interval are raised to that.
+func (l *RateLimiter) SetRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.set(rate, l.burst)
+}
+
+// SetBurst changes the bucket capacity (at least 1). Tokens above the new
+// capacity are dropped.
+func (l *RateLimiter) SetBurst(burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.set(l.rate, float64(burst))
+}
+
+// set applies rate and burst to the bucket. Called with mu held, or before
+// the limiter is shared.
+func (l *RateLimiter) set(rate, burst float64) {
+	l.rate = max(rate, 1e-9)
+	l.burst = max(burst, 1)
+	l.bucket.setRate(l.rate/float64(l.interval), l.burst)
+}
+
+// Rate returns the current rate in elements per Interval.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Burst returns the current bucket capacity.
+func (l *RateLimiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.burst)
+}
+
+// Allow takes a token if one is available now and reports whether it did.
+// It never blocks: use it to drop or defer work instead of waiting.
+func (l *RateLimiter) Allow() bool { return l.bucket.allow() }
+
+// Reserve takes a token now and returns how long to wait before acting on
+// it (0 if one was available). The token is spent even if the caller
+// gives up, so prefer Wait unless the delay is needed up front — e.g. to
+// decide between sleeping and failing fast.
+func (l *RateLimiter) Reserve() time.Duration { return l.bucket.reserve(1) }
+
+// ReserveN takes n tokens now (n may exceed the burst) and returns how long
+// to wait before using them, plus a function that refunds them.
+func (l *RateLimiter) ReserveN(n float64) (time.Duration, func()) {
+	return l.bucket.reserve(n), func() { l.bucket.refund(n) }
+}
+
+// WaitN blocks until n tokens are available, e.g. for a batch of n
+// requests. On cancellation the tokens are refunded and ctx.Err() is
+// returned.
+func (l *RateLimiter) WaitN(ctx context.Context, n float64) error { return waitReserved(ctx, l, n) }
+
+// Wait blocks until a token is available. It returns ctx.Err() if ctx is
+// cancelled first, without taking a token. A nil ctx waits indefinitely.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if !l.bucket.waitCtx(ctx) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// RateLimitWith throttles the pipeline with a shared Limiter — a
+// RateLimiter, WindowLimiter or MultiLimiter. Like RateLimit it is lazy and
+// stops waiting when the pipeline's context is cancelled.
+func (p *Pipeline[T]) RateLimitWith(l Limiter) *Pipeline[T] {
+	return &Pipeline[T]{
+		source: &costLimitSource[T]{
+			inner:   p.source,
+			limiter: l,
+			ctx:     p.ctx,
+		},
+		hooks:   p.hooks,
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Token bucket implementation
 // ---------------------------------------------------------------------------
@@ -185,6 +315,64 @@ func (tb *tokenBucket) refill() {
 	}
 }
 
+// delay returns how long refilling deficit tokens takes, capped so that
+// very low rates do not overflow time.Duration. Must be called with mu held.
+func (tb *tokenBucket) delay(deficit float64) time.Duration {
+	ns := deficit / tb.rate
+	if ns >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(ns)
+}
+
+// allow takes a token if one is available now.
+func (tb *tokenBucket) allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill()
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true
+	}
+	return false
+}
+
+// reserve takes n tokens unconditionally, letting the balance go negative,
+// and returns how long the caller must wait before using them. Later
+// callers queue behind the debt, so reservations are served in order, and
+// n may exceed the capacity.
+func (tb *tokenBucket) reserve(n float64) time.Duration {
+	n = clampCost(n)
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill()
+	tb.tokens -= n
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return tb.delay(-tb.tokens)
+}
+
+// refund gives back n reserved tokens, up to the capacity.
+func (tb *tokenBucket) refund(n float64) {
+	n = clampCost(n)
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill()
+	tb.tokens = min(tb.tokens+n, tb.max)
+}
+
+// setRate changes the refill rate (tokens per nanosecond) and capacity.
+// Tokens accrued at the old rate are kept, capped at the new capacity.
+func (tb *tokenBucket) setRate(rate, burst float64) {
+	tb.mu.Lock()
+	tb.refill()
+	tb.rate = rate
+	tb.max = burst
+	tb.tokens = min(tb.tokens, burst)
+	tb.mu.Unlock()
+}
+
 // wait blocks until a token is available. Not context-aware.
 func (tb *tokenBucket) wait() {
 	for {
@@ -196,10 +384,9 @@ func (tb *tokenBucket) wait() {
 			return
 		}
 		// Calculate wait time for next token
-		deficit := 1 - tb.tokens
-		waitNs := deficit / tb.rate
+		d := tb.delay(1 - tb.tokens)
 		tb.mu.Unlock()
-		time.Sleep(time.Duration(waitNs))
+		time.Sleep(d)
 	}
 }
 
@@ -218,11 +405,10 @@ func (tb *tokenBucket) waitCtx(ctx context.Context) bool {
 			tb.mu.Unlock()
 			return true
 		}
-		deficit := 1 - tb.tokens
-		waitNs := deficit / tb.rate
+		d := tb.delay(1 - tb.tokens)
 		tb.mu.Unlock()
 
-		timer := time.NewTimer(time.Duration(waitNs))
+		timer := time.NewTimer(d)
 		select {
 		case <-ctx.Done():
 			timer.Stop()
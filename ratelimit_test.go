@@ -306,3 +306,102 @@ func BenchmarkRateLimit_Overhead_vs_Plain(b *testing.B) {
 		}
 	})
 }
+
+// ---------------------------------------------------------------------------
+// Shared RateLimiter
+// ---------------------------------------------------------------------------
+
+func TestRateLimiter_AllowAndBurst(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 1, Interval: time.Hour, Burst: 3})
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("token %d refused", i)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("allowed beyond burst")
+	}
+	l.SetBurst(0)
+	if l.Burst() != 1 {
+		t.Errorf("burst clamped to %d", l.Burst())
+	}
+}
+
+func TestRateLimiter_ReserveQueues(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 10, Burst: 1})
+	if d := l.Reserve(); d != 0 {
+		t.Fatalf("first reservation waits %v", d)
+	}
+	d1, d2 := l.Reserve(), l.Reserve()
+	// Each reservation queues behind the previous one: ~100ms, ~200ms.
+	if d1 < 80*time.Millisecond || d1 > 110*time.Millisecond || d2 < d1+80*time.Millisecond {
+		t.Errorf("delays %v, %v", d1, d2)
+	}
+}
+
+func TestRateLimiter_SetRate(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 1, Interval: time.Hour, Burst: 1})
+	l.Allow()
+	l.SetRate(1000 * 3600) // 1000/s
+	if l.Rate() != 1000*3600 {
+		t.Fatalf("rate %v", l.Rate())
+	}
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("SetRate did not take effect: waited %v", time.Since(start))
+	}
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 1, Interval: time.Hour, Burst: 1})
+	l.Allow()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait = %v", err)
+	}
+}
+
+func TestRateLimitWith_SharedAcrossPipelines(t *testing.T) {
+	// 3 pipelines × 10 elements share 100/s with a burst of 10: ~200ms in
+	// total, where private limiters would finish in ~0.
+	l := NewRateLimiter(RateLimitConfig{Rate: 100, Burst: 10})
+	start := time.Now()
+	var total atomic.Int64
+	done := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		go func() {
+			total.Add(int64(FromRange(0, 10).RateLimitWith(l).Count()))
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+	elapsed := time.Since(start)
+	if total.Load() != 30 {
+		t.Fatalf("got %d elements", total.Load())
+	}
+	if elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("elapsed %v", elapsed)
+	}
+}
+
+func TestRateLimiter_InsideParallelStream(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Rate: 50, Burst: 1})
+	start := time.Now()
+	out := PipeMapParallelStream(FromRange(0, 6), 4, 4, func(n int) int {
+		l.Wait(context.Background())
+		return n
+	}).Collect()
+	if len(out) != 6 {
+		t.Fatalf("got %v", out)
+	}
+	// 1 burst token + 5 at 20ms each, regardless of the 4 workers.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("workers bypassed the shared limit: %v", elapsed)
+	}
+}
@@ -0,0 +1,254 @@
+package gosplice
+
+import "math"
+
+// RollingStat selects which statistics PipeRolling and PipeExpanding
+// compute. Combine with |; fields that were not requested are left zero.
+type RollingStat uint8
+
+const (
+	RollingMean RollingStat = 1 << iota
+	RollingSum
+	RollingStd
+	RollingMin
+	RollingMax
+
+	RollingAll = RollingMean | RollingSum | RollingStd | RollingMin | RollingMax
+)
+
+// Rolling is an input element together with statistics of fn over the
+// window ending at it.
+type Rolling[T any] struct {
+	Value T
+	Count int // values in the window; below size while the window fills up
+	Sum   float64
+	Mean  float64
+	Std   float64 // population standard deviation, like StdDevBy
+	Min   float64
+	Max   float64
+}
+
+// PipeRolling emits, for every element, statistics of fn over the last size
+// elements (fewer while the window fills up — check Count). Each update is
+// O(1) amortised: sums and Welford moments are updated incrementally and
+// min/max use monotonic deques, so cost does not depend on size. Unlike
+// PipeWindow no per-window slice is allocated. A NaN or ±Inf affects Sum,
+// Mean and Std only while it is in the window.
+func PipeRolling[T any](p *Pipeline[T], size int, fn func(T) float64, stats RollingStat) *Pipeline[Rolling[T]] {
+	return rolling(p, max(size, 1), fn, stats)
+}
+
+// PipeExpanding is PipeRolling over a window that never drops elements:
+// each output describes everything seen so far. O(1) memory.
+func PipeExpanding[T any](p *Pipeline[T], fn func(T) float64, stats RollingStat) *Pipeline[Rolling[T]] {
+	return rolling(p, 0, fn, stats)
+}
+
+func rolling[T any](p *Pipeline[T], size int, fn func(T) float64, stats RollingStat) *Pipeline[Rolling[T]] {
+	w := &rollingWindow{size: size, stats: stats}
+	if size > 0 {
+		w.ring = make([]float64, size)
+	}
+	return PipeScan(p, w, func(w *rollingWindow, v T) (*rollingWindow, Rolling[T]) {
+		x := fn(v) // may panic; the window is only touched after it returns
+		w.push(x)
+		return w, rollingResult(w, v)
+	})
+}
+
+// PipeEWMA emits the exponentially weighted moving mean (Mean) and standard
+// deviation (Std) of fn. Alpha is the smoothing factor, clamped to (0, 1]:
+// higher alpha reacts faster. The first element seeds the mean. Count is the
+// number of elements seen.
+func PipeEWMA[T any](p *Pipeline[T], alpha float64, fn func(T) float64) *Pipeline[Rolling[T]] {
+	alpha = clampAlpha(alpha)
+	return PipeScan(p, ewState{}, func(s ewState, v T) (ewState, Rolling[T]) {
+		s.add(fn(v), alpha)
+		return s, Rolling[T]{Value: v, Count: s.n, Mean: s.mean, Std: s.std()}
+	})
+}
+
+func clampAlpha(alpha float64) float64 {
+	return min(max(alpha, math.SmallestNonzeroFloat64), 1)
+}
+
+// ewState is an exponentially weighted mean and variance (Finch,
+// "Incremental calculation of weighted mean and variance"). The first value
+// seeds the mean.
+type ewState struct {
+	n        int
+	mean, vr float64
+}
+
+func (s *ewState) add(x, alpha float64) {
+	s.n++
+	if s.n == 1 {
+		s.mean = x
+		return
+	}
+	delta := x - s.mean
+	s.mean += alpha * delta
+	s.vr = (1 - alpha) * (s.vr + alpha*delta*delta)
+}
+
+func (s *ewState) std() float64 { return math.Sqrt(s.vr) }
+
+// rollingWindow keeps the running state for PipeRolling; size 0 means
+// expanding (nothing is ever evicted).
+type rollingWindow struct {
+	size  int
+	stats RollingStat
+	ring  []float64
+	seq   int // index of the next value
+	n     int // values in the window
+	sum   float64
+	mom   VarianceAcc // of the finite values only
+	minQ  monoDeque
+	maxQ  monoDeque
+	mm    MinMaxAcc // expanding min/max
+
+	// NaN and ±Inf in the window are counted instead of entering sum and
+	// mom, which could not recover once they leave.
+	nan, posInf, negInf int
+}
+
+func (w *rollingWindow) push(x float64) {
+	if w.size > 0 && w.seq >= w.size {
+		w.evict(w.ring[w.seq%w.size])
+	}
+	if w.size > 0 {
+		w.ring[w.seq%w.size] = x
+	}
+	w.n++
+	if !w.countNonFinite(x, 1) {
+		w.sum += x
+		w.mom.Add(x)
+	}
+
+	switch {
+	case w.size == 0:
+		if w.stats&(RollingMin|RollingMax) != 0 {
+			w.mm.Add(x)
+		}
+	default:
+		oldest := w.seq - w.size + 1
+		if w.stats&RollingMin != 0 {
+			w.minQ.push(w.seq, x, oldest, func(back, x float64) bool { return back >= x })
+		}
+		if w.stats&RollingMax != 0 {
+			w.maxQ.push(w.seq, x, oldest, func(back, x float64) bool { return back <= x })
+		}
+	}
+	w.seq++
+}
+
+// countNonFinite adds d to the counter of x if x is NaN or ±Inf, and
+// reports whether it was.
+func (w *rollingWindow) countNonFinite(x float64, d int) bool {
+	switch {
+	case math.IsNaN(x):
+		w.nan += d
+	case math.IsInf(x, 1):
+		w.posInf += d
+	case math.IsInf(x, -1):
+		w.negInf += d
+	default:
+		return false
+	}
+	return true
+}
+
+// evict removes x from the sum and reverses its Welford update.
+func (w *rollingWindow) evict(x float64) {
+	w.n--
+	if w.countNonFinite(x, -1) {
+		return
+	}
+	w.sum -= x
+	m := &w.mom
+	if m.Count == 1 {
+		*m = VarianceAcc{}
+		return
+	}
+	m.Count--
+	delta := x - m.Mean
+	m.Mean -= delta / float64(m.Count)
+	m.M2 -= delta * (x - m.Mean)
+	if m.M2 < 0 {
+		m.M2 = 0 // rounding
+	}
+}
+
+func rollingResult[T any](w *rollingWindow, v T) Rolling[T] {
+	r := Rolling[T]{Value: v, Count: w.n}
+	sum, mean, std := w.sum, w.mom.Mean, w.mom.StdDev()
+	// A NaN, or infinities of both signs, make the moments NaN; infinities
+	// of one sign make the sum and mean infinite and the spread NaN.
+	switch {
+	case w.nan > 0 || (w.posInf > 0 && w.negInf > 0):
+		sum, mean, std = math.NaN(), math.NaN(), math.NaN()
+	case w.posInf > 0:
+		sum, mean, std = math.Inf(1), math.Inf(1), math.NaN()
+	case w.negInf > 0:
+		sum, mean, std = math.Inf(-1), math.Inf(-1), math.NaN()
+	}
+	if w.stats&RollingSum != 0 {
+		r.Sum = sum
+	}
+	if w.stats&RollingMean != 0 {
+		r.Mean = mean
+	}
+	if w.stats&RollingStd != 0 {
+		r.Std = std
+	}
+	if w.size == 0 {
+		if w.stats&RollingMin != 0 {
+			r.Min = w.mm.Min
+		}
+		if w.stats&RollingMax != 0 {
+			r.Max = w.mm.Max
+		}
+		return r
+	}
+	if w.stats&RollingMin != 0 {
+		r.Min = w.minQ.front()
+	}
+	if w.stats&RollingMax != 0 {
+		r.Max = w.maxQ.front()
+	}
+	return r
+}
+
+// monoDeque is a monotonic deque over a sliding window: the front is always
+// the window's min (or max). Each value is pushed and popped at most once,
+// so updates are O(1) amortised.
+type monoDeque struct {
+	items []monoItem
+	head  int
+}
+
+type monoItem struct {
+	i int
+	x float64
+}
+
+// push adds value x at index i, dropping values from the back that can no
+// longer be the extreme (dominated(back, x)) and from the front that fell
+// out of the window (index < oldest).
+func (d *monoDeque) push(i int, x float64, oldest int, dominated func(back, x float64) bool) {
+	for len(d.items) > d.head && dominated(d.items[len(d.items)-1].x, x) {
+		d.items = d.items[:len(d.items)-1]
+	}
+	d.items = append(d.items, monoItem{i: i, x: x})
+	for d.items[d.head].i < oldest {
+		d.head++
+	}
+	// Reclaim the consumed prefix once it dominates the slice.
+	if d.head > 64 && d.head*2 > len(d.items) {
+		n := copy(d.items, d.items[d.head:])
+		d.items = d.items[:n]
+		d.head = 0
+	}
+}
+
+func (d *monoDeque) front() float64 { return d.items[d.head].x }
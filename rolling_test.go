@@ -0,0 +1,135 @@
+package gosplice
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bruteRolling recomputes every window from scratch.
+func bruteRolling(data []float64, size int) []Rolling[float64] {
+	out := make([]Rolling[float64], len(data))
+	for i := range data {
+		lo := 0
+		if size > 0 {
+			lo = max(0, i-size+1)
+		}
+		win := data[lo : i+1]
+		r := Rolling[float64]{Value: data[i], Count: len(win), Min: win[0], Max: win[0]}
+		for _, x := range win {
+			r.Sum += x
+			r.Min = math.Min(r.Min, x)
+			r.Max = math.Max(r.Max, x)
+		}
+		r.Mean = Mean(win)
+		r.Std = StdDev(win)
+		out[i] = r
+	}
+	return out
+}
+
+func checkRolling(t *testing.T, got, want []Rolling[float64]) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d outputs, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Count != w.Count || g.Min != w.Min || g.Max != w.Max || g.Value != w.Value ||
+			!approxEqual(g.Sum, w.Sum, 1e-6) || !approxEqual(g.Mean, w.Mean, 1e-6) || !approxEqual(g.Std, w.Std, 1e-6) {
+			t.Fatalf("at %d: got %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestPipeRolling_MatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	data := make([]float64, 2000)
+	for i := range data {
+		data[i] = r.NormFloat64()*10 + float64(i%50)
+	}
+	id := func(x float64) float64 { return x }
+	for _, size := range []int{1, 3, 20, 500} {
+		checkRolling(t, PipeRolling(FromSlice(data), size, id, RollingAll).Collect(), bruteRolling(data, size))
+	}
+	checkRolling(t, PipeExpanding(FromSlice(data), id, RollingAll).Collect(), bruteRolling(data, 0))
+}
+
+func TestPipeRolling_MonotonicInputs(t *testing.T) {
+	// Increasing then decreasing runs exercise both deques' eviction paths.
+	var data []float64
+	for i := 0; i < 300; i++ {
+		data = append(data, float64(i))
+	}
+	for i := 300; i > 0; i-- {
+		data = append(data, float64(i))
+	}
+	id := func(x float64) float64 { return x }
+	checkRolling(t, PipeRolling(FromSlice(data), 7, id, RollingAll).Collect(), bruteRolling(data, 7))
+}
+
+func TestPipeRolling_RecoversAfterNonFinite(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	data := []float64{1, 2, nan, 4, 5, 6, inf, 8, 9, 10}
+	out := PipeRolling(FromSlice(data), 3, func(x float64) float64 { return x }, RollingAll).Collect()
+	want := bruteRolling(data, 3)
+	for i, g := range out {
+		w := want[i]
+		// The brute force sums NaN and Inf like any value, so the two agree
+		// both while they are in the window and after they leave it.
+		same := func(a, b float64) bool {
+			return (math.IsNaN(a) && math.IsNaN(b)) || a == b || approxEqual(a, b, 1e-9)
+		}
+		if g.Count != w.Count || !same(g.Sum, w.Sum) || !same(g.Mean, w.Mean) || !same(g.Std, w.Std) {
+			t.Errorf("at %d: got %+v, want %+v", i, g, w)
+		}
+	}
+	if last := out[len(out)-1]; last.Sum != 27 || last.Mean != 9 {
+		t.Errorf("window after the Inf left: %+v", last)
+	}
+}
+
+func TestPipeRolling_OnlyRequestedStats(t *testing.T) {
+	out := PipeRolling(FromSlice([]float64{1, 2, 3}), 2, func(x float64) float64 { return x }, RollingMax|RollingMean).Collect()
+	last := out[2]
+	if last.Max != 3 || last.Mean != 2.5 || last.Sum != 0 || last.Min != 0 || last.Std != 0 {
+		t.Errorf("got %+v", last)
+	}
+}
+
+func TestPipeEWMA(t *testing.T) {
+	out := PipeEWMA(FromSlice([]float64{10, 20, 20, 20}), 0.5, func(x float64) float64 { return x }).Collect()
+	wantMean := []float64{10, 15, 17.5, 18.75}
+	for i, r := range out {
+		if !approxEqual(r.Mean, wantMean[i], eps) || r.Count != i+1 {
+			t.Errorf("at %d: got %+v", i, r)
+		}
+	}
+	if out[0].Std != 0 || out[1].Std == 0 || out[3].Std >= out[1].Std {
+		t.Errorf("std should start at 0, jump, then decay: %v %v %v", out[0].Std, out[1].Std, out[3].Std)
+	}
+
+	flat := PipeEWMA(FromSlice([]float64{5, 5, 5}), 0.3, func(x float64) float64 { return x }).Collect()
+	if flat[2].Mean != 5 || flat[2].Std != 0 {
+		t.Errorf("constant input: %+v", flat[2])
+	}
+}
+
+func TestPipeRolling_PanicLeavesWindowIntact(t *testing.T) {
+	p := FromSlice([]float64{1, 2, -1, 3}).WithPanicRecovery()
+	r := PipeRolling(p, 2, func(x float64) float64 {
+		if x < 0 {
+			panic("negative")
+		}
+		return x
+	}, RollingSum)
+	out := r.Collect()
+	if len(out) != 3 || out[2].Sum != 5 || out[2].Count != 2 {
+		t.Errorf("got %+v", out)
+	}
+	var pe *PanicError
+	if !errors.As(r.Err(), &pe) {
+		t.Errorf("Err() = %v", r.Err())
+	}
+}
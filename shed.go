@@ -0,0 +1,147 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+)
+
+// ---------------------------------------------------------------------------
+// Load shedding
+// ---------------------------------------------------------------------------
+
+// ShedPolicy selects what a streaming stage does when its internal buffer
+// is full — when downstream (workers or the consumer) cannot keep up with
+// the source. It applies to PipeMapParallelStreamWith and to PipeBatch with
+// MaxWait.
+type ShedPolicy uint8
+
+const (
+	// ShedBlock waits for room, pushing back on the source. The default.
+	ShedBlock ShedPolicy = iota
+
+	// ShedDropNewest drops the element that does not fit and reports it to
+	// the pipeline's shed hooks (WithShedHook).
+	ShedDropNewest
+
+	// ShedDropOldest drops the oldest buffered element to make room, so the
+	// freshest data wins — the usual choice for metrics and quotes.
+	ShedDropOldest
+
+	// ShedReject drops the element that does not fit and routes ErrLoadShed
+	// through the error handler or error hooks; Abort stops the stage and
+	// Err() reports ErrLoadShed.
+	ShedReject
+)
+
+// ErrLoadShed is reported for elements rejected under ShedReject.
+var ErrLoadShed = errors.New("gosplice: element rejected under load")
+
+// shedOne disposes of one element that did not fit under policy. Returns
+// true if the error handler asked to abort, after recording ErrLoadShed in
+// errs.
+func shedOne[T any](h *Hooks[T], policy ShedPolicy, v T, errs *errSlot) (abort bool) {
+	if policy == ShedReject {
+		if h.handleError(ErrLoadShed, v, 1) == Abort {
+			errs.set(ErrLoadShed)
+			return true
+		}
+		return false
+	}
+	h.fireShed(v)
+	return false
+}
+
+// ---------------------------------------------------------------------------
+// ConcurrencyLimiter
+// ---------------------------------------------------------------------------
+
+// ConcurrencyLimiter caps how many operations are in flight at once — a
+// counting semaphore. Rate limiters bound how often work starts; this
+// bounds how much runs concurrently, e.g. open connections to a database.
+// Share one limiter across pipelines and workers. Safe for concurrent use.
+//
+//	db := gs.NewConcurrencyLimiter(8)
+//	gs.PipeMapParallelStream(queries, 32, 64, func(q Query) Result {
+//	    if err := db.Acquire(ctx); err != nil {
+//	        return Result{Err: err}
+//	    }
+//	    defer db.Release()
+//	    return run(q)
+//	})
+type ConcurrencyLimiter struct {
+	sem chan struct{}
+}
+
+// NewConcurrencyLimiter allows at most n (at least 1) operations in flight.
+func NewConcurrencyLimiter(n int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{sem: make(chan struct{}, max(n, 1))}
+}
+
+// Acquire blocks until a slot is free. It returns ctx.Err() if ctx is
+// cancelled first; a nil ctx waits indefinitely. Every successful Acquire
+// must be paired with Release.
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	if ctx == nil {
+		c.sem <- struct{}{}
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes a slot if one is free, without blocking.
+func (c *ConcurrencyLimiter) TryAcquire() bool {
+	select {
+	case c.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot taken by Acquire or TryAcquire.
+func (c *ConcurrencyLimiter) Release() { <-c.sem }
+
+// InFlight returns the number of slots currently taken.
+func (c *ConcurrencyLimiter) InFlight() int { return len(c.sem) }
+
+// Limit returns the maximum number of slots.
+func (c *ConcurrencyLimiter) Limit() int { return cap(c.sem) }
+
+// LimitConcurrency wraps fn so that every call holds a slot of l, for use
+// with the parallel stages: PipeMapParallel(p, 32, LimitConcurrency(db, query)).
+// A call waits for a slot without regard to any context, so under a
+// cancelled pipeline it still blocks until a slot frees up; use
+// LimitConcurrencyCtx with the ctx stages to stop waiting on cancellation.
+func LimitConcurrency[T any, U any](l *ConcurrencyLimiter, fn func(T) U) func(T) U {
+	return func(v T) U {
+		l.Acquire(nil)
+		defer l.Release()
+		return fn(v)
+	}
+}
+
+// LimitConcurrencyCtx is LimitConcurrency for functions that take a
+// context, such as those of PipeMapCtx and PipeMapParallelStreamCtx. A call
+// waits for a slot only while ctx is live and otherwise fails with
+// ctx.Err() without calling fn.
+//
+//	rows := gs.PipeMapParallelStreamCtx(queries.WithTimeout(time.Minute), 32, 64,
+//	    gs.LimitConcurrencyCtx(db, runQuery))
+func LimitConcurrencyCtx[T any, U any](l *ConcurrencyLimiter, fn func(context.Context, T) (U, error)) func(context.Context, T) (U, error) {
+	return func(ctx context.Context, v T) (U, error) {
+		if err := l.Acquire(ctx); err != nil {
+			var zero U
+			return zero, err
+		}
+		defer l.Release()
+		return fn(ctx, v)
+	}
+}
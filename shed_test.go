@@ -0,0 +1,323 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// shedLog records shed elements from the stage's goroutine.
+type shedLog struct {
+	mu   sync.Mutex
+	vals []int
+}
+
+func (l *shedLog) add(v int) {
+	l.mu.Lock()
+	l.vals = append(l.vals, v)
+	l.mu.Unlock()
+}
+
+func (l *shedLog) waitLen(t *testing.T, n int) []int {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		l.mu.Lock()
+		got := slices.Clone(l.vals)
+		l.mu.Unlock()
+		if len(got) >= n || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrencyLimiter_BoundsInFlight(t *testing.T) {
+	l := NewConcurrencyLimiter(3)
+	var cur, peak atomic.Int32
+	fn := LimitConcurrency(l, func(v int) int {
+		n := cur.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		cur.Add(-1)
+		return v * 2
+	})
+	out := PipeMapParallel(FromSlice(makeRange(40)), 16, fn).Collect()
+	if len(out) != 40 || out[39] != 78 {
+		t.Fatalf("got %v", out)
+	}
+	if p := peak.Load(); p > 3 || p < 2 {
+		t.Errorf("peak in flight %d, want <= 3", p)
+	}
+	if l.InFlight() != 0 || l.Limit() != 3 {
+		t.Errorf("in flight %d, limit %d", l.InFlight(), l.Limit())
+	}
+}
+
+func TestConcurrencyLimiter_AcquireCtx(t *testing.T) {
+	l := NewConcurrencyLimiter(1)
+	if !l.TryAcquire() {
+		t.Fatal("TryAcquire on empty limiter failed")
+	}
+	if l.TryAcquire() {
+		t.Fatal("TryAcquire on full limiter succeeded")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire on full limiter: %v", err)
+	}
+	l.Release()
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Errorf("Acquire after Release: %v", err)
+	}
+}
+
+// runShedStream feeds 0..9 to a one-worker stream with a queue of 2. The
+// worker blocks on element 0 until every other element has been queued or
+// shed, so exactly elements 1..9 compete for the two queue slots.
+func runShedStream(t *testing.T, policy ShedPolicy, shedN int) ([]int, []int) {
+	t.Helper()
+	ch := make(chan int)
+	started, release := make(chan struct{}), make(chan struct{})
+	var shed shedLog
+	out := PipeMapParallelStreamWith(FromChannel(ch).WithShedHook(shed.add), StreamConfig{Workers: 1, Buffer: 2, Shed: policy}, func(v int) int {
+		if v == 0 {
+			close(started)
+			<-release
+		}
+		return v
+	})
+
+	ch <- 0
+	<-started
+	for i := 1; i < 10; i++ {
+		ch <- i
+	}
+	close(ch)
+	got := shed.waitLen(t, shedN)
+	close(release)
+	return out.Collect(), got
+}
+
+func TestShed_ParallelStreamDropNewest(t *testing.T) {
+	out, shed := runShedStream(t, ShedDropNewest, 7)
+	if !slices.Equal(out, []int{0, 1, 2}) || !slices.Equal(shed, []int{3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("out %v, shed %v", out, shed)
+	}
+}
+
+func TestShed_ParallelStreamDropOldest(t *testing.T) {
+	out, shed := runShedStream(t, ShedDropOldest, 7)
+	if !slices.Equal(out, []int{0, 8, 9}) || !slices.Equal(shed, []int{1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("out %v, shed %v", out, shed)
+	}
+}
+
+func TestShed_ParallelStreamReject(t *testing.T) {
+	var mu sync.Mutex
+	var rejected []int
+	h := func(err error, v int, _ int) ErrorAction {
+		if !errors.Is(err, ErrLoadShed) {
+			t.Errorf("unexpected error %v", err)
+		}
+		mu.Lock()
+		rejected = append(rejected, v)
+		mu.Unlock()
+		return Skip
+	}
+	// Rejected elements go to the error handler, not the shed hooks.
+	ch := make(chan int)
+	started, release := make(chan struct{}), make(chan struct{})
+	out := PipeMapParallelStreamWith(FromChannel(ch).WithErrorHandler(h),
+		StreamConfig{Workers: 1, Buffer: 2, Shed: ShedReject}, func(v int) int {
+			if v == 0 {
+				close(started)
+				<-release
+			}
+			return v
+		})
+	ch <- 0
+	<-started
+	for i := 1; i < 10; i++ {
+		ch <- i
+	}
+	close(ch)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(rejected)
+		mu.Unlock()
+		if n >= 7 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	got := out.Collect()
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(got, []int{0, 1, 2}) || !slices.Equal(rejected, []int{3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("out %v, rejected %v", got, rejected)
+	}
+}
+
+func TestShed_ParallelStreamRejectAbort(t *testing.T) {
+	var calls atomic.Int32
+	h := func(err error, _ int, _ int) ErrorAction {
+		calls.Add(1)
+		return Abort
+	}
+	// An endless source and a slow worker: the first rejection stops it.
+	i := 0
+	src := FromFunc(func() (int, bool) { i++; return i, true }).WithErrorHandler(h)
+	p := PipeMapParallelStreamWith(src, StreamConfig{Workers: 1, Buffer: 1, Shed: ShedReject}, func(v int) int {
+		time.Sleep(10 * time.Millisecond)
+		return v
+	})
+	done := make(chan []int)
+	go func() { done <- p.Collect() }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not stop after Abort")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times", calls.Load())
+	}
+	if !errors.Is(p.Err(), ErrLoadShed) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
+
+func TestShed_BatchRejectAbortReportsErr(t *testing.T) {
+	ch := make(chan int)
+	p := PipeBatch(FromChannel(ch).WithErrorHandler(func(error, int, int) ErrorAction { return Abort }),
+		BatchConfig{Size: 1, MaxWait: time.Hour, Shed: ShedReject})
+	go func() {
+		// The consumer never reads, so the output buffer fills and the
+		// next batch is rejected.
+		for i := 0; ; i++ {
+			select {
+			case ch <- i:
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+	bs := p.source.(*batchChanSource[int])
+	deadline := time.Now().Add(2 * time.Second)
+	for bs.Err() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	p.Close()
+	if !errors.Is(p.Err(), ErrLoadShed) {
+		t.Errorf("Err() = %v", p.Err())
+	}
+}
+
+func TestShed_ParallelStreamWaitsForReader(t *testing.T) {
+	var hooked atomic.Int32
+	parked, release := make(chan struct{}), make(chan struct{})
+	i := 0
+	src := FromFunc(func() (int, bool) {
+		i++
+		if i == 3 {
+			close(parked)
+			<-release
+		}
+		return i, i <= 3
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	p := PipeMapParallelStreamWith(src.WithContext(ctx).WithElementHook(func(int) { hooked.Add(1) }),
+		StreamConfig{Workers: 1, Buffer: 1, Shed: ShedDropNewest}, func(v int) int { return v })
+	ss := p.source.(*stoppableSource[int])
+	<-parked
+	cancel()
+
+	// The reader is parked in Next: the stage must not finish before it.
+	deadline := time.After(20 * time.Millisecond)
+wait:
+	for {
+		select {
+		case _, ok := <-ss.ch:
+			if !ok {
+				t.Fatal("output closed while the reader was still running")
+			}
+		case <-deadline:
+			break wait
+		}
+	}
+	close(release)
+	for range ss.ch {
+	}
+	if n := hooked.Load(); n != 3 {
+		t.Errorf("element hook fired %d times", n)
+	}
+}
+
+func TestLimitConcurrencyCtx(t *testing.T) {
+	l := NewConcurrencyLimiter(1)
+	var calls atomic.Int32
+	fn := LimitConcurrencyCtx(l, func(_ context.Context, v int) (int, error) {
+		calls.Add(1)
+		return v, nil
+	})
+	if v, err := fn(context.Background(), 7); v != 7 || err != nil || l.InFlight() != 0 {
+		t.Fatalf("got %d, %v with %d in flight", v, err, l.InFlight())
+	}
+
+	l.TryAcquire()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := fn(ctx, 8); !errors.Is(err, context.DeadlineExceeded) || calls.Load() != 1 {
+		t.Errorf("full limiter: err %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestShed_BatchDropNewestAndOldest(t *testing.T) {
+	for _, tc := range []struct {
+		policy ShedPolicy
+		out    []int
+		shed   []int
+	}{
+		// Four batches fit in the output buffer while nobody consumes.
+		{ShedDropNewest, []int{0, 1, 2, 3}, []int{4, 5, 6, 7, 8, 9}},
+		{ShedDropOldest, []int{6, 7, 8, 9}, []int{0, 1, 2, 3, 4, 5}},
+	} {
+		ch := make(chan int)
+		var shed shedLog
+		batches := PipeBatch(FromChannel(ch).WithShedHook(shed.add),
+			BatchConfig{Size: 1, MaxWait: time.Hour, Shed: tc.policy})
+		for i := 0; i < 10; i++ {
+			ch <- i
+		}
+		got := shed.waitLen(t, 6)
+		close(ch)
+		var out []int
+		for _, b := range batches.Collect() {
+			out = append(out, b...)
+		}
+		if !slices.Equal(out, tc.out) || !slices.Equal(got, tc.shed) {
+			t.Errorf("policy %d: out %v, shed %v", tc.policy, out, got)
+		}
+	}
+}
+
+func TestShed_BlockIsDefault(t *testing.T) {
+	out := PipeMapParallelStreamWith(FromSlice(makeRange(100)), StreamConfig{Workers: 4, Buffer: 2}, func(v int) int {
+		return v + 1
+	}).Collect()
+	if len(out) != 100 || out[0] != 1 || out[99] != 100 {
+		t.Errorf("got %d elements", len(out))
+	}
+}
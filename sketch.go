@@ -0,0 +1,508 @@
+package gosplice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+var errSketchMismatch = errors.New("gosplice: cannot merge sketches with different parameters")
+
+// KeyHash returns a 64-bit hash of k that is stable across processes, so
+// sketches built from it on different machines or runs can be merged.
+// Strings, integers, floats and bools are hashed directly; other types by
+// their %#v representation.
+func KeyHash[K comparable](k K) uint64 {
+	switch v := any(k).(type) {
+	case string:
+		return hashString(v)
+	case int:
+		return mix64(uint64(v))
+	case int8:
+		return mix64(uint64(v))
+	case int16:
+		return mix64(uint64(v))
+	case int32:
+		return mix64(uint64(v))
+	case int64:
+		return mix64(uint64(v))
+	case uint:
+		return mix64(uint64(v))
+	case uint8:
+		return mix64(uint64(v))
+	case uint16:
+		return mix64(uint64(v))
+	case uint32:
+		return mix64(uint64(v))
+	case uint64:
+		return mix64(v)
+	case uintptr:
+		return mix64(uint64(v))
+	case float32:
+		return mix64(uint64(math.Float32bits(v)))
+	case float64:
+		return mix64(math.Float64bits(v))
+	case bool:
+		if v {
+			return mix64(1)
+		}
+		return mix64(0)
+	}
+	return hashString(fmt.Sprintf("%#v", k))
+}
+
+// hashString is FNV-1a followed by a finaliser; FNV alone does not spread
+// short keys across the high bits that HyperLogLog indexes by.
+func hashString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return mix64(h)
+}
+
+// mix64 is the MurmurHash3 64-bit finaliser.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// --- HyperLogLog ---
+
+// DefaultHLLPrecision is the HyperLogLog precision used by ApproxCountDistinctBy:
+// 2^14 one-byte registers (16 KiB), about 0.8% standard error.
+const DefaultHLLPrecision = 14
+
+// HyperLogLog estimates the number of distinct keys in fixed memory
+// (2^precision bytes). Standard error is 1.04/√(2^precision). Sketches with
+// the same precision merge losslessly, so per-worker or per-day sketches can
+// be combined. Not safe for concurrent use.
+type HyperLogLog struct {
+	p    uint8
+	regs []uint8
+}
+
+// NewHyperLogLog returns an empty sketch. Precision is clamped to [4, 18].
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	precision = min(max(precision, 4), 18)
+	return &HyperLogLog{p: precision, regs: make([]uint8, 1<<precision)}
+}
+
+// Add records a string key.
+func (h *HyperLogLog) Add(key string) { h.AddHash(hashString(key)) }
+
+// AddHash records a pre-hashed key (see KeyHash).
+func (h *HyperLogLog) AddHash(x uint64) {
+	idx := x >> (64 - h.p)
+	rho := uint8(64-h.p) + 1
+	if w := x << h.p; w != 0 {
+		rho = uint8(bits.LeadingZeros64(w)) + 1
+	}
+	if rho > h.regs[idx] {
+		h.regs[idx] = rho
+	}
+}
+
+// Merge folds other into h. Both must have the same precision.
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if other.p != h.p {
+		return errSketchMismatch
+	}
+	for i, r := range other.regs {
+		if r > h.regs[i] {
+			h.regs[i] = r
+		}
+	}
+	return nil
+}
+
+// Count returns the estimated number of distinct keys.
+//
+// Uses Ertl's improved raw estimator ("New cardinality estimation algorithms
+// for HyperLogLog sketches", 2017), which stays unbiased from empty to very
+// large cardinalities without empirical bias tables.
+func (h *HyperLogLog) Count() int {
+	q := 64 - int(h.p)
+	m := float64(len(h.regs))
+	hist := make([]float64, q+2)
+	for _, r := range h.regs {
+		hist[r]++
+	}
+	z := m * hllTau(1-hist[q+1]/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + hist[k])
+	}
+	z += m * hllSigma(hist[0]/m)
+	return int(math.Round(m * m / (2 * math.Ln2) / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
+
+const hllVersion = 1
+
+// MarshalBinary encodes the sketch. Also used by encoding/gob.
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 2+len(h.regs))
+	b = append(b, hllVersion, h.p)
+	return append(b, h.regs...), nil
+}
+
+// UnmarshalBinary restores a sketch written by MarshalBinary.
+func (h *HyperLogLog) UnmarshalBinary(b []byte) error {
+	if len(b) < 2 || b[0] != hllVersion || b[1] < 4 || b[1] > 18 || len(b)-2 != 1<<b[1] {
+		return errSketchCorrupt
+	}
+	h.p = b[1]
+	h.regs = slices.Clone(b[2:])
+	return nil
+}
+
+// --- Count-Min ---
+
+// CountMinSketch estimates per-key frequencies in fixed memory. Estimates
+// never undercount; with probability 1-delta they overcount by at most
+// epsilon × Total(). Sketches with the same dimensions merge by addition.
+// Not safe for concurrent use.
+type CountMinSketch struct {
+	width, depth int
+	counts       []uint64
+	total        uint64
+}
+
+// NewCountMinSketch sizes a sketch for the given error bound and failure
+// probability: width ⌈e/epsilon⌉, depth ⌈ln(1/delta)⌉.
+func NewCountMinSketch(epsilon, delta float64) *CountMinSketch {
+	w := int(math.Ceil(math.E / epsilon))
+	d := int(math.Ceil(math.Log(1 / delta)))
+	return NewCountMinSketchSize(max(w, 1), max(d, 1))
+}
+
+// NewCountMinSketchSize returns a sketch with explicit dimensions, each at
+// least 1.
+func NewCountMinSketchSize(width, depth int) *CountMinSketch {
+	width, depth = max(width, 1), max(depth, 1)
+	return &CountMinSketch{width: width, depth: depth, counts: make([]uint64, width*depth)}
+}
+
+// Add adds n occurrences of a string key and returns its new estimate.
+func (c *CountMinSketch) Add(key string, n uint64) uint64 { return c.AddHash(hashString(key), n) }
+
+// AddHash adds n occurrences of a pre-hashed key (see KeyHash) and returns its new estimate.
+func (c *CountMinSketch) AddHash(x, n uint64) uint64 {
+	c.total += n
+	est := uint64(math.MaxUint64)
+	for i := 0; i < c.depth; i++ {
+		j := c.cell(x, i)
+		c.counts[j] += n
+		est = min(est, c.counts[j])
+	}
+	return est
+}
+
+// Count returns the estimated frequency of a string key.
+func (c *CountMinSketch) Count(key string) uint64 { return c.CountHash(hashString(key)) }
+
+// CountHash returns the estimated frequency of a pre-hashed key.
+func (c *CountMinSketch) CountHash(x uint64) uint64 {
+	est := uint64(math.MaxUint64)
+	for i := 0; i < c.depth; i++ {
+		est = min(est, c.counts[c.cell(x, i)])
+	}
+	return est
+}
+
+// Total returns the sum of all added counts.
+func (c *CountMinSketch) Total() uint64 { return c.total }
+
+// cell derives row i's column from one hash (Kirsch–Mitzenmacher double hashing).
+func (c *CountMinSketch) cell(x uint64, i int) int {
+	h1, h2 := x&0xffffffff, x>>32|1
+	return i*c.width + int((h1+uint64(i)*h2)%uint64(c.width))
+}
+
+// Merge adds other's counts into c. Both must have the same dimensions.
+func (c *CountMinSketch) Merge(other *CountMinSketch) error {
+	if other.width != c.width || other.depth != c.depth {
+		return errSketchMismatch
+	}
+	for i, n := range other.counts {
+		c.counts[i] += n
+	}
+	c.total += other.total
+	return nil
+}
+
+const cmsVersion = 1
+
+// MarshalBinary encodes the sketch. Also used by encoding/gob.
+func (c *CountMinSketch) MarshalBinary() ([]byte, error) {
+	b := []byte{cmsVersion}
+	b = binary.AppendUvarint(b, uint64(c.width))
+	b = binary.AppendUvarint(b, uint64(c.depth))
+	b = binary.AppendUvarint(b, c.total)
+	for _, n := range c.counts {
+		b = binary.AppendUvarint(b, n)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary restores a sketch written by MarshalBinary.
+func (c *CountMinSketch) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 || b[0] != cmsVersion {
+		return errSketchCorrupt
+	}
+	b = b[1:]
+	var hdr [3]uint64
+	for i := range hdr {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errSketchCorrupt
+		}
+		hdr[i], b = v, b[n:]
+	}
+	// Every count takes at least one byte, so each dimension and their
+	// product are bounded by the bytes left. Dividing instead of
+	// multiplying keeps a crafted header from overflowing past the check.
+	rest := uint64(len(b))
+	if hdr[0] == 0 || hdr[1] == 0 || hdr[0] > rest || hdr[1] > rest/hdr[0] {
+		return errSketchCorrupt
+	}
+	counts := make([]uint64, hdr[0]*hdr[1])
+	for i := range counts {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errSketchCorrupt
+		}
+		counts[i], b = v, b[n:]
+	}
+	if len(b) != 0 {
+		return errSketchCorrupt
+	}
+	c.width, c.depth, c.total, c.counts = int(hdr[0]), int(hdr[1]), hdr[2], counts
+	return nil
+}
+
+// --- Heavy hitters ---
+
+// HeavyHitter is a key with its estimated frequency (an upper bound).
+type HeavyHitter[K comparable] struct {
+	Key   K
+	Count uint64
+}
+
+// HeavyHitters tracks the k most frequent keys of a stream: a CountMinSketch
+// estimates every key's frequency and the k keys with the highest estimates
+// are kept as candidates. Memory is the sketch plus k keys, independent of
+// the number of distinct keys. Not safe for concurrent use.
+type HeavyHitters[K comparable] struct {
+	k      int
+	cms    *CountMinSketch
+	top    map[K]uint64
+	minKey K
+	minCnt uint64
+	minOK  bool
+}
+
+// NewHeavyHitters tracks the top k keys (at least 1); epsilon and delta
+// size the underlying CountMinSketch (see NewCountMinSketch).
+func NewHeavyHitters[K comparable](k int, epsilon, delta float64) *HeavyHitters[K] {
+	k = max(k, 1)
+	return &HeavyHitters[K]{
+		k:   k,
+		cms: NewCountMinSketch(epsilon, delta),
+		top: make(map[K]uint64, k),
+	}
+}
+
+// Add records one occurrence of key.
+func (h *HeavyHitters[K]) Add(key K) { h.addN(key, 1) }
+
+func (h *HeavyHitters[K]) addN(key K, n uint64) {
+	est := h.cms.AddHash(KeyHash(key), n)
+	if _, ok := h.top[key]; ok {
+		h.top[key] = est
+		if h.minOK && key == h.minKey {
+			h.minOK = false
+		}
+		return
+	}
+	if len(h.top) < h.k {
+		h.top[key] = est
+		h.minOK = false
+		return
+	}
+	h.findMin()
+	if est <= h.minCnt {
+		return
+	}
+	delete(h.top, h.minKey)
+	h.top[key] = est
+	h.minOK = false
+}
+
+// findMin caches the lowest-count candidate; it is only recomputed after the
+// candidate set or the current minimum changes.
+func (h *HeavyHitters[K]) findMin() {
+	if h.minOK {
+		return
+	}
+	first := true
+	for k, c := range h.top {
+		if first || c < h.minCnt {
+			h.minKey, h.minCnt, first = k, c, false
+		}
+	}
+	h.minOK = true
+}
+
+// Top returns the candidates, most frequent first. Keys with equal counts
+// are in unspecified order.
+func (h *HeavyHitters[K]) Top() []HeavyHitter[K] {
+	out := make([]HeavyHitter[K], 0, len(h.top))
+	for k, c := range h.top {
+		out = append(out, HeavyHitter[K]{Key: k, Count: c})
+	}
+	slices.SortFunc(out, func(a, b HeavyHitter[K]) int {
+		switch {
+		case a.Count > b.Count:
+			return -1
+		case a.Count < b.Count:
+			return 1
+		}
+		return 0
+	})
+	return out
+}
+
+// Total returns the number of keys added.
+func (h *HeavyHitters[K]) Total() uint64 { return h.cms.Total() }
+
+// Merge folds other into h. The sketches must have the same dimensions;
+// candidates from both are re-estimated against the merged sketch and the
+// best k kept.
+func (h *HeavyHitters[K]) Merge(other *HeavyHitters[K]) error {
+	if err := h.cms.Merge(other.cms); err != nil {
+		return err
+	}
+	for k := range other.top {
+		h.top[k] = 0
+	}
+	for k := range h.top {
+		h.top[k] = h.cms.CountHash(KeyHash(k))
+	}
+	if len(h.top) > h.k {
+		for _, hh := range h.Top()[h.k:] {
+			delete(h.top, hh.Key)
+		}
+	}
+	h.minOK = false
+	return nil
+}
+
+type heavyHittersWire[K comparable] struct {
+	K      int
+	Sketch []byte
+	Keys   []K
+	Counts []uint64
+}
+
+// MarshalBinary encodes the tracker with encoding/gob, so K must be gob-encodable.
+func (h *HeavyHitters[K]) MarshalBinary() ([]byte, error) {
+	sk, err := h.cms.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	w := heavyHittersWire[K]{K: h.k, Sketch: sk}
+	for k, c := range h.top {
+		w.Keys = append(w.Keys, k)
+		w.Counts = append(w.Counts, c)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(w); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary restores a tracker written by MarshalBinary.
+func (h *HeavyHitters[K]) UnmarshalBinary(b []byte) error {
+	var w heavyHittersWire[K]
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&w); err != nil {
+		return err
+	}
+	if w.K < 1 || len(w.Keys) != len(w.Counts) || len(w.Keys) > w.K {
+		return errSketchCorrupt
+	}
+	cms := new(CountMinSketch)
+	if err := cms.UnmarshalBinary(w.Sketch); err != nil {
+		return err
+	}
+	*h = HeavyHitters[K]{k: w.K, cms: cms, top: make(map[K]uint64, w.K)}
+	for i, k := range w.Keys {
+		h.top[k] = w.Counts[i]
+	}
+	return nil
+}
+
+// --- Pipeline aggregations ---
+
+// ApproxCountDistinctBy estimates the number of distinct keys with a
+// HyperLogLog sketch (DefaultHLLPrecision): fixed 16 KiB of memory instead
+// of an exact set of every key.
+func ApproxCountDistinctBy[T any, K comparable](p *Pipeline[T], keyFn func(T) K) int {
+	defer p.finalize()
+	h := NewHyperLogLog(DefaultHLLPrecision)
+	drain(p, func(v T) { h.AddHash(KeyHash(keyFn(v))) })
+	return h.Count()
+}
+
+// HeavyHittersBy returns the k most frequent keys with estimated counts,
+// most frequent first, in memory independent of the number of distinct keys.
+// Counts are upper bounds, off by at most 0.1% of the stream length with 99%
+// probability. Use NewHeavyHitters directly for other bounds or to merge.
+func HeavyHittersBy[T any, K comparable](p *Pipeline[T], k int, keyFn func(T) K) []HeavyHitter[K] {
+	defer p.finalize()
+	h := NewHeavyHitters[K](k, 0.001, 0.01)
+	drain(p, func(v T) { h.Add(keyFn(v)) })
+	return h.Top()
+}
@@ -0,0 +1,227 @@
+package gosplice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestKeyHash_Stable(t *testing.T) {
+	if KeyHash("abc") != KeyHash("abc") || KeyHash(42) != KeyHash(42) {
+		t.Fatal("hash not deterministic")
+	}
+	if KeyHash("abc") == KeyHash("abd") {
+		t.Fatal("distinct strings collided")
+	}
+	type pair struct{ A, B int }
+	if KeyHash(pair{1, 2}) != KeyHash(pair{1, 2}) || KeyHash(pair{1, 2}) == KeyHash(pair{2, 1}) {
+		t.Fatal("struct keys should hash by value")
+	}
+}
+
+func TestHyperLogLog_Accuracy(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 5_000, 50_000, 500_000} {
+		h := NewHyperLogLog(DefaultHLLPrecision)
+		for i := 0; i < n; i++ {
+			h.AddHash(KeyHash(i))
+			h.AddHash(KeyHash(i)) // duplicates must not count
+		}
+		got := h.Count()
+		if n == 0 {
+			if got != 0 {
+				t.Errorf("empty: got %d", got)
+			}
+			continue
+		}
+		if rel := math.Abs(float64(got-n)) / float64(n); rel > 0.03 {
+			t.Errorf("n=%d: got %d (%.2f%% off)", n, got, rel*100)
+		}
+	}
+}
+
+func TestHyperLogLog_MergeAndSerialise(t *testing.T) {
+	a, b := NewHyperLogLog(12), NewHyperLogLog(12)
+	for i := 0; i < 30_000; i++ {
+		a.Add(fmt.Sprint("user-", i))
+		b.Add(fmt.Sprint("user-", i+15_000)) // half overlaps
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Count(); math.Abs(float64(got-45_000))/45_000 > 0.05 {
+		t.Errorf("merged count = %d, want ~45000", got)
+	}
+	if err := a.Merge(NewHyperLogLog(10)); err == nil {
+		t.Error("merging different precisions should fail")
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a); err != nil {
+		t.Fatal(err)
+	}
+	var restored HyperLogLog
+	if err := gob.NewDecoder(&buf).Decode(&restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Count() != a.Count() {
+		t.Errorf("restored %d, original %d", restored.Count(), a.Count())
+	}
+	if err := restored.UnmarshalBinary([]byte{hllVersion, 12, 0}); err == nil {
+		t.Error("short encoding should fail")
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	c := NewCountMinSketch(0.001, 0.01)
+	for i := 0; i < 10_000; i++ {
+		c.Add(fmt.Sprint(i%1000), 1)
+	}
+	c.Add("hot", 5_000)
+	if got := c.Count("hot"); got < 5_000 || got > 5_000+15 {
+		t.Errorf("hot = %d", got)
+	}
+	if got := c.Count("7"); got < 10 || got > 10+15 {
+		t.Errorf("7 = %d", got)
+	}
+	if c.Total() != 15_000 {
+		t.Errorf("total = %d", c.Total())
+	}
+
+	other := NewCountMinSketch(0.001, 0.01)
+	other.Add("hot", 1)
+	if err := c.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Count("hot"); got < 5_001 {
+		t.Errorf("merged hot = %d", got)
+	}
+	if err := c.Merge(NewCountMinSketchSize(10, 2)); err == nil {
+		t.Error("merging different dimensions should fail")
+	}
+
+	b, _ := c.MarshalBinary()
+	var restored CountMinSketch
+	if err := restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Count("hot") != c.Count("hot") || restored.Total() != c.Total() {
+		t.Error("round trip changed estimates")
+	}
+	if err := restored.UnmarshalBinary(b[:len(b)-1]); err == nil {
+		t.Error("truncated encoding should fail")
+	}
+}
+
+func TestCountMinSketch_UnmarshalOverflowingHeader(t *testing.T) {
+	// 3 × 0xAAAAAAAAAAAAAAAB wraps to 1, which a multiplied length check
+	// would accept with a single count byte.
+	b := []byte{cmsVersion}
+	b = binary.AppendUvarint(b, 3)
+	b = binary.AppendUvarint(b, 0xAAAAAAAAAAAAAAAB)
+	b = binary.AppendUvarint(b, 1)
+	b = append(b, 1)
+	var c CountMinSketch
+	if err := c.UnmarshalBinary(b); err == nil {
+		t.Fatalf("accepted %dx%d sketch with one count", c.width, c.depth)
+	}
+}
+
+func TestCountMinSketchSize_ClampsDimensions(t *testing.T) {
+	c := NewCountMinSketchSize(0, -3)
+	if c.width != 1 || c.depth != 1 {
+		t.Fatalf("got %dx%d", c.width, c.depth)
+	}
+	if got := c.Add("a", 2); got != 2 {
+		t.Errorf("estimate %d", got)
+	}
+}
+
+func TestHeavyHitters_UnmarshalRejectsBadK(t *testing.T) {
+	sk, _ := NewCountMinSketchSize(4, 2).MarshalBinary()
+	for _, w := range []heavyHittersWire[string]{
+		{K: -1, Sketch: sk},
+		{K: 0, Sketch: sk},
+		{K: 1, Sketch: sk, Keys: []string{"a", "b"}, Counts: []uint64{1, 1}},
+	} {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(w); err != nil {
+			t.Fatal(err)
+		}
+		var h HeavyHitters[string]
+		if err := h.UnmarshalBinary(buf.Bytes()); err != errSketchCorrupt {
+			t.Errorf("K=%d with %d keys: got %v", w.K, len(w.Keys), err)
+		}
+	}
+}
+
+// zipfish yields key i roughly 1000/(i+1) times, interleaved.
+func zipfish(keys int) []string {
+	var out []string
+	for round := 0; round < 1000; round++ {
+		for i := 0; i < keys; i++ {
+			if round%(i+1) == 0 {
+				out = append(out, fmt.Sprint("k", i))
+			}
+		}
+	}
+	return out
+}
+
+func TestHeavyHittersBy(t *testing.T) {
+	data := zipfish(2000)
+	got := HeavyHittersBy(FromSlice(data), 3, func(s string) string { return s })
+	if len(got) != 3 {
+		t.Fatalf("got %d hitters", len(got))
+	}
+	for i, want := range []string{"k0", "k1", "k2"} {
+		if got[i].Key != want {
+			t.Errorf("rank %d: got %v, want %s", i, got[i], want)
+		}
+	}
+	if got[0].Count < 1000 || got[0].Count > 1000+uint64(len(data))/1000 {
+		t.Errorf("k0 count = %d", got[0].Count)
+	}
+}
+
+func TestHeavyHitters_MergeAndSerialise(t *testing.T) {
+	a := NewHeavyHitters[int](2, 0.01, 0.01)
+	b := NewHeavyHitters[int](2, 0.01, 0.01)
+	for i := 0; i < 100; i++ {
+		a.Add(1)
+		b.Add(2)
+		a.Add(3)
+		b.Add(3)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	top := a.Top()
+	if len(top) != 2 || top[0].Key != 3 || top[0].Count < 200 {
+		t.Fatalf("merged top = %v", top)
+	}
+
+	enc, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored HeavyHitters[int]
+	if err := restored.UnmarshalBinary(enc); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(restored.Top()[0]) != fmt.Sprint(top[0]) || restored.Total() != a.Total() {
+		t.Errorf("restored top = %v, total %d", restored.Top(), restored.Total())
+	}
+}
+
+func TestApproxCountDistinctBy(t *testing.T) {
+	got := ApproxCountDistinctBy(FromRange(0, 200_000), func(n int) int { return n % 50_000 })
+	if math.Abs(float64(got-50_000))/50_000 > 0.03 {
+		t.Errorf("got %d, want ~50000", got)
+	}
+	if ApproxCountDistinctBy(FromSlice([]string{}), func(s string) string { return s }) != 0 {
+		t.Error("empty input should count 0")
+	}
+}
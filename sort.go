@@ -0,0 +1,525 @@
+package gosplice
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ---------------------------------------------------------------------------
+// Codec — element serialisation for spilling to disk
+// ---------------------------------------------------------------------------
+
+// Codec serialises elements for spilling to disk (PipeSortExternal) and
+// other places where values must leave memory. Each call handles one value;
+// framing is done by the caller.
+type Codec[T any] interface {
+	Encode(T) ([]byte, error)
+	Decode([]byte) (T, error)
+}
+
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Encode(v T) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec[T]) Decode(b []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// JSONCodec encodes elements with encoding/json. T's exported fields round-trip.
+func JSONCodec[T any]() Codec[T] { return jsonCodec[T]{} }
+
+type gobCodec[T any] struct{}
+
+func (gobCodec[T]) Encode(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec[T]) Decode(b []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	return v, err
+}
+
+// GobCodec encodes elements with encoding/gob. Each Encode result is
+// self-contained; PipeSortExternal instead keeps one encoder and decoder per
+// run file, so gob's type information is written once per run.
+func GobCodec[T any]() Codec[T] { return gobCodec[T]{} }
+
+func (gobCodec[T]) newStream() Codec[T] { return &gobStream[T]{} }
+
+// streamCodec is implemented by codecs that can share state across the
+// values of one stream, written and then read back in order.
+type streamCodec[T any] interface {
+	newStream() Codec[T]
+}
+
+// gobStream is a GobCodec for one stream: the first value carries the type
+// information and later values only their data. Encode's result is valid
+// until the next Encode.
+type gobStream[T any] struct {
+	enc  *gob.Encoder
+	ebuf bytes.Buffer
+	dec  *gob.Decoder
+	dbuf bytes.Buffer
+}
+
+func (g *gobStream[T]) Encode(v T) ([]byte, error) {
+	if g.enc == nil {
+		g.enc = gob.NewEncoder(&g.ebuf)
+	}
+	g.ebuf.Reset()
+	err := g.enc.Encode(v)
+	return g.ebuf.Bytes(), err
+}
+
+func (g *gobStream[T]) Decode(b []byte) (T, error) {
+	if g.dec == nil {
+		g.dec = gob.NewDecoder(&g.dbuf)
+	}
+	g.dbuf.Write(b)
+	var v T
+	err := g.dec.Decode(&v)
+	return v, err
+}
+
+// ---------------------------------------------------------------------------
+// SortConfig
+// ---------------------------------------------------------------------------
+
+// SortConfig controls PipeSortExternal.
+type SortConfig[T any] struct {
+	// MaxItems is the number of elements held in memory before the buffer
+	// is sorted and spilled to a temp file as a run. Zero means no limit.
+	MaxItems int
+
+	// MaxBytes spills when the estimated buffer size exceeds this many bytes.
+	// Requires SizeOf. Zero means no limit.
+	MaxBytes int64
+
+	// SizeOf estimates the in-memory size of an element for MaxBytes.
+	SizeOf func(T) int
+
+	// Codec serialises spilled elements. Defaults to GobCodec.
+	Codec Codec[T]
+
+	// TempDir is where run files are created. Defaults to os.TempDir().
+	TempDir string
+}
+
+func (c SortConfig[T]) codec() Codec[T] {
+	if c.Codec == nil {
+		return GobCodec[T]()
+	}
+	return c.Codec
+}
+
+// runCodec returns the codec for one run file.
+func (c SortConfig[T]) runCodec() Codec[T] {
+	codec := c.codec()
+	if sc, ok := codec.(streamCodec[T]); ok {
+		return sc.newStream()
+	}
+	return codec
+}
+
+func (c SortConfig[T]) full(items int, size int64) bool {
+	if c.MaxItems > 0 && items >= c.MaxItems {
+		return true
+	}
+	return c.MaxBytes > 0 && c.SizeOf != nil && size >= c.MaxBytes
+}
+
+// ---------------------------------------------------------------------------
+// Pipeline stages
+// ---------------------------------------------------------------------------
+
+// DefaultSortMaxItems is the memory budget of PipeSort, in elements.
+const DefaultSortMaxItems = 1_000_000
+
+// PipeSort is a stable sort. Like the parallel stages it is a barrier: the
+// first element is produced only after the whole input has been read.
+// Sorting is lazy — nothing happens until a terminal runs. Inputs of up to
+// DefaultSortMaxItems elements are sorted in memory; larger ones spill
+// sorted runs to temp files with GobCodec, so T must then be gob-encodable.
+// Use PipeSortExternal for another budget or codec.
+func PipeSort[T any](p *Pipeline[T], less func(a, b T) bool) *Pipeline[T] {
+	return PipeSortExternal(p, less, SortConfig[T]{MaxItems: DefaultSortMaxItems})
+}
+
+// PipeSortExternal is a stable external merge sort. Elements are buffered
+// until cfg.MaxItems or cfg.MaxBytes is reached, then the buffer is sorted
+// and written to a temp file through cfg.Codec. Once the input is exhausted
+// the runs are merged with a k-way heap merge, so memory holds one buffer
+// plus one element per run.
+//
+// Spill I/O and codec errors stop the stage and are reported by Err().
+// Temp files are removed when the merge finishes or the pipeline is closed.
+func PipeSortExternal[T any](p *Pipeline[T], less func(a, b T) bool, cfg SortConfig[T]) *Pipeline[T] {
+	return &Pipeline[T]{
+		source:  &sortSource[T]{inner: p.source, less: less, cfg: cfg, ctx: p.ctx},
+		hooks:   p.hooks,
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+type sortSource[T any] struct {
+	inner  Source[T]
+	less   func(a, b T) bool
+	cfg    SortConfig[T]
+	ctx    context.Context
+	inited bool
+
+	// in-memory result (no spill)
+	buf []T
+	idx int
+
+	// external merge
+	runs []*sortRun[T]
+	heap []*sortRun[T]
+
+	// mu guards the run files so Close can remove them while Next runs.
+	mu        sync.Mutex
+	err       errSlot
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (s *sortSource[T]) Next() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.inited {
+		s.inited = true
+		s.load()
+	}
+	var zero T
+	if s.runs == nil {
+		if s.idx >= len(s.buf) {
+			return zero, false
+		}
+		v := s.buf[s.idx]
+		s.buf[s.idx] = zero // release for GC as we go
+		s.idx++
+		return v, true
+	}
+	if len(s.heap) == 0 {
+		s.removeRuns()
+		return zero, false
+	}
+	top := s.heap[0]
+	v := top.head
+	if err := top.advance(); err != nil {
+		s.err.set(err)
+		s.removeRuns()
+		return zero, false
+	}
+	if top.done {
+		last := len(s.heap) - 1
+		s.heap[0] = s.heap[last]
+		s.heap = s.heap[:last]
+	}
+	if len(s.heap) > 0 {
+		heapDown(s.heap, 0, s.before)
+	}
+	return v, true
+}
+
+// load drains the input, spilling sorted runs when the buffer is full.
+func (s *sortSource[T]) load() {
+	var done <-chan struct{}
+	if s.ctx != nil {
+		done = s.ctx.Done()
+	}
+	var size int64
+	for i := 0; ; i++ {
+		if done != nil && i&(ctxCheckInterval-1) == 0 {
+			select {
+			case <-done:
+				s.removeRuns()
+				s.buf = nil
+				return
+			default:
+			}
+		}
+		v, ok := s.inner.Next()
+		if !ok {
+			break
+		}
+		s.buf = append(s.buf, v)
+		if s.cfg.SizeOf != nil {
+			size += int64(s.cfg.SizeOf(v))
+		}
+		if s.cfg.full(len(s.buf), size) {
+			if err := s.spill(); err != nil {
+				s.err.set(err)
+				s.removeRuns()
+				s.buf = nil
+				return
+			}
+			size = 0
+		}
+	}
+	if s.runs == nil {
+		slices.SortStableFunc(s.buf, s.cmp)
+		return
+	}
+	if len(s.buf) > 0 {
+		if err := s.spill(); err != nil {
+			s.err.set(err)
+			s.removeRuns()
+			return
+		}
+	}
+	s.buf = nil
+	if err := s.startMerge(); err != nil {
+		s.err.set(err)
+		s.removeRuns()
+	}
+}
+
+func (s *sortSource[T]) cmp(a, b T) int {
+	switch {
+	case s.less(a, b):
+		return -1
+	case s.less(b, a):
+		return 1
+	}
+	return 0
+}
+
+func (s *sortSource[T]) spill() error {
+	slices.SortStableFunc(s.buf, s.cmp)
+	f, err := os.CreateTemp(s.cfg.TempDir, "gosplice-sort-*")
+	if err != nil {
+		return fmt.Errorf("gosplice: sort spill: %w", err)
+	}
+	run := &sortRun[T]{file: f, seq: len(s.runs), codec: s.cfg.runCodec()}
+	s.runs = append(s.runs, run)
+
+	w := bufio.NewWriter(f)
+	codec := run.codec
+	var lenBuf [binary.MaxVarintLen64]byte
+	for i, v := range s.buf {
+		b, err := codec.Encode(v)
+		if err != nil {
+			return fmt.Errorf("gosplice: sort encode: %w", err)
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+		if _, err := w.Write(lenBuf[:n]); err != nil {
+			return fmt.Errorf("gosplice: sort spill: %w", err)
+		}
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("gosplice: sort spill: %w", err)
+		}
+		var zero T
+		s.buf[i] = zero
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("gosplice: sort spill: %w", err)
+	}
+	s.buf = s.buf[:0]
+	return nil
+}
+
+func (s *sortSource[T]) startMerge() error {
+	s.heap = make([]*sortRun[T], 0, len(s.runs))
+	for _, run := range s.runs {
+		if _, err := run.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("gosplice: sort merge: %w", err)
+		}
+		run.r = bufio.NewReader(run.file)
+		if err := run.advance(); err != nil {
+			return err
+		}
+		if !run.done {
+			s.heap = append(s.heap, run)
+		}
+	}
+	for i := len(s.heap)/2 - 1; i >= 0; i-- {
+		heapDown(s.heap, i, s.before)
+	}
+	return nil
+}
+
+// before orders runs by head element, then by run sequence to keep the merge stable.
+func (s *sortSource[T]) before(a, b *sortRun[T]) bool {
+	if c := s.cmp(a.head, b.head); c != 0 {
+		return c < 0
+	}
+	return a.seq < b.seq
+}
+
+func (s *sortSource[T]) removeRuns() {
+	for _, run := range s.runs {
+		run.file.Close()
+		os.Remove(run.file.Name())
+	}
+	s.runs = s.runs[:0]
+	s.heap = nil
+}
+
+func (s *sortSource[T]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+// Close removes any remaining temp files and closes upstream.
+func (s *sortSource[T]) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeErr = closeSource(s.inner)
+		s.mu.Lock()
+		s.removeRuns()
+		s.mu.Unlock()
+	})
+	return s.closeErr
+}
+
+// sortRun is one sorted spill file being merged.
+type sortRun[T any] struct {
+	file  *os.File
+	r     *bufio.Reader
+	codec Codec[T] // encodes and then decodes this run's elements
+	seq   int
+	head  T
+	done  bool
+}
+
+func (r *sortRun[T]) advance() error {
+	n, err := binary.ReadUvarint(r.r)
+	if errors.Is(err, io.EOF) {
+		var zero T
+		r.head = zero
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("gosplice: sort merge: %w", err)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return fmt.Errorf("gosplice: sort merge: %w", err)
+	}
+	v, err := r.codec.Decode(b)
+	if err != nil {
+		return fmt.Errorf("gosplice: sort decode: %w", err)
+	}
+	r.head = v
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Top-K
+// ---------------------------------------------------------------------------
+
+// TopK returns the k largest elements by less, largest first, using a bounded
+// heap of size k — O(n log k) time and O(k) memory. Ties keep arrival order.
+func TopK[T any](p *Pipeline[T], k int, less func(a, b T) bool) []T {
+	return boundedK(p, k, func(a, b T) bool { return less(b, a) })
+}
+
+// BottomK returns the k smallest elements by less, smallest first.
+func BottomK[T any](p *Pipeline[T], k int, less func(a, b T) bool) []T {
+	return boundedK(p, k, less)
+}
+
+type rankedItem[T any] struct {
+	v   T
+	seq int
+}
+
+// boundedK keeps the k best elements, where better(a, b) means a ranks before b.
+// The heap root is the worst element kept, so each new element is compared once.
+func boundedK[T any](p *Pipeline[T], k int, better func(a, b T) bool) []T {
+	defer p.finalize()
+	if k <= 0 {
+		return nil
+	}
+	// worse orders by rank, falling back to arrival so earlier ties win.
+	worse := func(a, b rankedItem[T]) bool {
+		if better(b.v, a.v) {
+			return true
+		}
+		if better(a.v, b.v) {
+			return false
+		}
+		return a.seq > b.seq
+	}
+	h := make([]rankedItem[T], 0, k)
+	seq := 0
+	drain(p, func(v T) {
+		it := rankedItem[T]{v: v, seq: seq}
+		seq++
+		if len(h) < k {
+			h = append(h, it)
+			heapUp(h, len(h)-1, worse)
+			return
+		}
+		if worse(h[0], it) {
+			h[0] = it
+			heapDown(h, 0, worse)
+		}
+	})
+	slices.SortFunc(h, func(a, b rankedItem[T]) int {
+		switch {
+		case worse(b, a):
+			return -1
+		case worse(a, b):
+			return 1
+		}
+		return 0
+	})
+	out := make([]T, len(h))
+	for i, it := range h {
+		out[i] = it.v
+	}
+	return out
+}
+
+// heapUp and heapDown maintain a binary heap whose root is the element for
+// which top(root, x) holds against every other x.
+func heapUp[E any](h []E, i int, top func(a, b E) bool) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !top(h[i], h[parent]) {
+			return
+		}
+		h[i], h[parent] = h[parent], h[i]
+		i = parent
+	}
+}
+
+func heapDown[E any](h []E, i int, top func(a, b E) bool) {
+	for {
+		l := 2*i + 1
+		if l >= len(h) {
+			return
+		}
+		m := l
+		if r := l + 1; r < len(h) && top(h[r], h[l]) {
+			m = r
+		}
+		if !top(h[m], h[i]) {
+			return
+		}
+		h[i], h[m] = h[m], h[i]
+		i = m
+	}
+}
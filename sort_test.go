@@ -0,0 +1,221 @@
+package gosplice
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type sortRec struct {
+	Key int
+	Seq int
+}
+
+func byKey(a, b sortRec) bool { return a.Key < b.Key }
+
+func TestPipeSortInMemory(t *testing.T) {
+	got := PipeSort(FromSlice([]int{5, 3, 9, 1, 3}), func(a, b int) bool { return a < b }).Collect()
+	assertSliceEqual(t, []int{1, 3, 3, 5, 9}, got)
+}
+
+func TestPipeSortEmpty(t *testing.T) {
+	if got := PipeSort(FromSlice([]int{}), func(a, b int) bool { return a < b }).Collect(); len(got) != 0 {
+		t.Errorf("expected empty, got %v", got)
+	}
+}
+
+func TestPipeSortIsLazy(t *testing.T) {
+	pulled := 0
+	p := PipeSort(FromFunc(func() (int, bool) {
+		pulled++
+		return pulled, pulled <= 3
+	}), func(a, b int) bool { return a > b })
+	if pulled != 0 {
+		t.Fatal("sort must not read before a terminal runs")
+	}
+	assertSliceEqual(t, []int{3, 2, 1}, p.Collect())
+}
+
+func TestPipeSortExternalSpills(t *testing.T) {
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(1))
+	in := make([]sortRec, 1000)
+	for i := range in {
+		in[i] = sortRec{Key: rng.Intn(50), Seq: i}
+	}
+
+	p := PipeSortExternal(FromSlice(in), byKey, SortConfig[sortRec]{MaxItems: 64, TempDir: dir})
+	first, ok := p.source.Next()
+	if !ok {
+		t.Fatal("expected output")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "gosplice-sort-*"))
+	if len(files) < 15 {
+		t.Errorf("expected spilled runs, found %d files", len(files))
+	}
+
+	got := append([]sortRec{first}, p.Collect()...)
+	want := slices.Clone(in)
+	slices.SortStableFunc(want, func(a, b sortRec) int { return a.Key - b.Key })
+	assertSliceEqual(t, want, got)
+	if p.Err() != nil {
+		t.Fatal(p.Err())
+	}
+
+	files, _ = filepath.Glob(filepath.Join(dir, "gosplice-sort-*"))
+	if len(files) != 0 {
+		t.Errorf("expected temp files removed, found %v", files)
+	}
+}
+
+func TestPipeSortSpillsPastDefaultBudget(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir) // os.TempDir, where PipeSort spills
+	n := DefaultSortMaxItems + 10
+	p := PipeSort(FromRange(0, n), func(a, b int) bool { return a > b })
+	first, ok := p.source.Next()
+	if !ok || first != n-1 {
+		t.Fatalf("first = %d, %v", first, ok)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "gosplice-sort-*")); len(files) != 2 {
+		t.Errorf("expected 2 spilled runs, found %d files", len(files))
+	}
+	got := p.Collect()
+	if len(got) != n-1 || got[0] != n-2 || got[len(got)-1] != 0 || !slices.IsSortedFunc(got, func(a, b int) int { return b - a }) {
+		t.Errorf("got %d elements, not sorted descending", len(got))
+	}
+	if p.Err() != nil {
+		t.Fatal(p.Err())
+	}
+}
+
+func TestPipeSortExternalJSONCodecAndMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	in := []string{"pear", "apple", "fig", "kiwi", "banana", "cherry", "date"}
+	got := PipeSortExternal(FromSlice(in), func(a, b string) bool { return a < b }, SortConfig[string]{
+		MaxBytes: 10,
+		SizeOf:   func(s string) int { return len(s) },
+		Codec:    JSONCodec[string](),
+		TempDir:  dir,
+	}).Collect()
+	assertSliceEqual(t, []string{"apple", "banana", "cherry", "date", "fig", "kiwi", "pear"}, got)
+}
+
+func TestPipeSortExternalCloseRemovesRuns(t *testing.T) {
+	dir := t.TempDir()
+	p := PipeSortExternal(FromRange(0, 100), func(a, b int) bool { return a > b },
+		SortConfig[int]{MaxItems: 10, TempDir: dir})
+	if v, ok := p.source.Next(); !ok || v != 99 {
+		t.Fatalf("expected 99, got %d %v", v, ok)
+	}
+	p.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 0 {
+		t.Errorf("expected no files after Close, found %v", files)
+	}
+}
+
+type failCodec struct{}
+
+func (failCodec) Encode(int) ([]byte, error) { return nil, errors.New("encode failed") }
+func (failCodec) Decode([]byte) (int, error) { return 0, nil }
+
+func TestPipeSortExternalCodecError(t *testing.T) {
+	dir := t.TempDir()
+	p := PipeSortExternal(FromRange(0, 10), func(a, b int) bool { return a < b },
+		SortConfig[int]{MaxItems: 2, Codec: failCodec{}, TempDir: dir})
+	if got := p.Collect(); len(got) != 0 {
+		t.Errorf("expected no output, got %v", got)
+	}
+	if p.Err() == nil {
+		t.Error("expected codec error")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected temp files removed after error, found %d", len(entries))
+	}
+}
+
+func TestPipeSortCtxCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := PipeSort(FromRange(0, 100).WithContext(ctx), func(a, b int) bool { return a < b })
+	if got := p.Collect(); len(got) != 0 {
+		t.Errorf("expected empty, got %d", len(got))
+	}
+	if !errors.Is(p.Err(), context.Canceled) {
+		t.Errorf("expected Canceled, got %v", p.Err())
+	}
+}
+
+func TestTopK(t *testing.T) {
+	got := TopK(FromSlice([]int{5, 1, 9, 3, 7, 9, 2}), 3, func(a, b int) bool { return a < b })
+	assertSliceEqual(t, []int{9, 9, 7}, got)
+}
+
+func TestBottomK(t *testing.T) {
+	got := BottomK(FromSlice([]int{5, 1, 9, 3, 7, 9, 2}), 3, func(a, b int) bool { return a < b })
+	assertSliceEqual(t, []int{1, 2, 3}, got)
+}
+
+func TestTopKStableTies(t *testing.T) {
+	in := []sortRec{{1, 0}, {2, 1}, {2, 2}, {0, 3}, {2, 4}}
+	got := TopK(FromSlice(in), 2, byKey)
+	assertSliceEqual(t, []sortRec{{2, 1}, {2, 2}}, got)
+}
+
+func TestTopKFewerThanK(t *testing.T) {
+	got := TopK(FromSlice([]int{2, 1}), 5, func(a, b int) bool { return a < b })
+	assertSliceEqual(t, []int{2, 1}, got)
+	if TopK(FromSlice([]int{1}), 0, func(a, b int) bool { return a < b }) != nil {
+		t.Error("expected nil for k=0")
+	}
+}
+
+func TestTopKMatchesSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	in := make([]int, 500)
+	for i := range in {
+		in[i] = rng.Intn(1000)
+	}
+	want := slices.Clone(in)
+	slices.Sort(want)
+	slices.Reverse(want)
+	assertSliceEqual(t, want[:20], TopK(FromSlice(in), 20, func(a, b int) bool { return a < b }))
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	for name, c := range map[string]Codec[sortRec]{"json": JSONCodec[sortRec](), "gob": GobCodec[sortRec]()} {
+		b, err := c.Encode(sortRec{Key: 4, Seq: 2})
+		if err != nil {
+			t.Fatalf("%s encode: %v", name, err)
+		}
+		v, err := c.Decode(b)
+		if err != nil || v != (sortRec{Key: 4, Seq: 2}) {
+			t.Errorf("%s round trip: %v %v", name, v, err)
+		}
+	}
+}
+
+func TestGobStreamSendsTypeOnce(t *testing.T) {
+	enc := GobCodec[sortRec]().(gobCodec[sortRec]).newStream()
+	dec := GobCodec[sortRec]().(gobCodec[sortRec]).newStream()
+	var sizes []int
+	for i := range 3 {
+		b, err := enc.Encode(sortRec{Key: i, Seq: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(b))
+		v, err := dec.Decode(b)
+		if err != nil || v != (sortRec{Key: i, Seq: i}) {
+			t.Fatalf("round trip %d: %v %v", i, v, err)
+		}
+	}
+	if sizes[1] >= sizes[0] || sizes[2] != sizes[1] {
+		t.Errorf("expected type info in the first value only, sizes %v", sizes)
+	}
+}
@@ -115,10 +115,32 @@ func (s *readerSource) Err() error { return s.err }
 
 // FromReader creates a pipeline that yields one string per line (splits on \n).
 // Check pipeline.Err() after the terminal call for I/O errors.
+// The reader is not closed by the pipeline; use FromReadCloser for that.
 func FromReader(r io.Reader) *Pipeline[string] {
 	return newPipeline[string](&readerSource{scanner: bufio.NewScanner(r)})
 }
 
+type readCloserSource struct {
+	readerSource
+	rc        io.ReadCloser
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (s *readCloserSource) Close() error {
+	s.closeOnce.Do(func() { s.closeErr = s.rc.Close() })
+	return s.closeErr
+}
+
+// FromReadCloser is like FromReader but the pipeline owns rc: it is closed
+// by Pipeline.Close, and automatically when Take, First or Any stop early.
+func FromReadCloser(rc io.ReadCloser) *Pipeline[string] {
+	return newPipeline[string](&readCloserSource{
+		readerSource: readerSource{scanner: bufio.NewScanner(rc)},
+		rc:           rc,
+	})
+}
+
 type funcSource[T any] struct {
 	fn func() (T, bool)
 }
@@ -129,6 +151,12 @@ func FromFunc[T any](fn func() (T, bool)) *Pipeline[T] {
 	return newPipeline[T](&funcSource[T]{fn: fn})
 }
 
+// FromSource wraps a custom Source implementation in a pipeline.
+// If src also has an Err() error method, its error is reported by pipeline.Err().
+func FromSource[T any](src Source[T]) *Pipeline[T] {
+	return newPipeline[T](src)
+}
+
 type rangeSource struct {
 	cur int
 	end int
@@ -154,6 +182,18 @@ func FromRange(start, end int) *Pipeline[int] {
 	return newPipeline[int](&rangeSource{cur: start, end: end})
 }
 
+// closeSource closes src if it implements io.Closer. Stage sources implement
+// Close by closing their inner source, so closing the last stage releases
+// the whole chain. Close implementations must be idempotent and safe to call
+// while another goroutine is inside Next — closing is how a background
+// reader parked in Next gets unblocked.
+func closeSource(src any) error {
+	if c, ok := src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 func sizeHint[T any](src Source[T]) int {
 	if s, ok := src.(Sizer); ok {
 		return s.SizeHint()
@@ -5,6 +5,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFuncSourceExhausted(t *testing.T) {
@@ -182,3 +183,54 @@ func TestDrainSourceCtx_SizerNonSlice(t *testing.T) {
 	).Collect()
 	assertSliceEqual(t, []int{0, 2, 4, 6, 8}, result)
 }
+
+type errAfterSource struct {
+	n   int
+	err error
+}
+
+func (s *errAfterSource) Next() (int, bool) {
+	if s.n == 0 {
+		s.err = io.ErrUnexpectedEOF
+		return 0, false
+	}
+	s.n--
+	return s.n, true
+}
+
+func (s *errAfterSource) Err() error { return s.err }
+
+func TestFromSourcePropagatesErr(t *testing.T) {
+	p := FromSource[int](&errAfterSource{n: 3})
+	assertSliceEqual(t, []int{2, 1, 0}, p.Collect())
+	if p.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("expected ErrUnexpectedEOF, got %v", p.Err())
+	}
+}
+
+func TestSourceErrSurfacesThroughStages(t *testing.T) {
+	p := PipeMap(FromSource[int](&errAfterSource{n: 3}).Filter(func(n int) bool { return n > 0 }),
+		func(n int) int { return n * 2 }).Take(5)
+	assertSliceEqual(t, []int{4, 2}, p.Collect())
+	if p.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("expected ErrUnexpectedEOF through stages, got %v", p.Err())
+	}
+}
+
+func TestSourceErrSurfacesThroughBatchTimeout(t *testing.T) {
+	p := PipeBatch(FromSource[int](&errAfterSource{n: 3}), BatchConfig{Size: 2, MaxWait: time.Second})
+	if got := p.Collect(); len(got) != 2 {
+		t.Errorf("expected 2 batches, got %v", got)
+	}
+	if p.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("expected ErrUnexpectedEOF through PipeBatch, got %v", p.Err())
+	}
+}
+
+func TestSourceErrSurfacesThroughParallelStream(t *testing.T) {
+	p := PipeMapParallelStream(FromSource[int](&errAfterSource{n: 3}), 2, 2, func(n int) int { return n })
+	assertSliceEqual(t, []int{2, 1, 0}, p.Collect())
+	if p.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("expected ErrUnexpectedEOF through PipeMapParallelStream, got %v", p.Err())
+	}
+}
@@ -1,8 +1,15 @@
 package gosplice
 
+import (
+	"context"
+	"sync"
+)
+
 type filterSource[T any] struct {
 	inner Source[T]
 	pred  func(T) bool
+	hooks *Hooks[T]
+	err   errSlot
 }
 
 func (s *filterSource[T]) Next() (T, bool) {
@@ -12,13 +19,40 @@ func (s *filterSource[T]) Next() (T, bool) {
 			var zero T
 			return zero, false
 		}
+		if s.hooks.RecoverPanics {
+			keep, perr, abort := callGuarded(s.hooks, s.pred, v)
+			if perr != nil {
+				s.err.set(perr)
+				if abort {
+					var zero T
+					return zero, false
+				}
+				continue
+			}
+			if keep {
+				return v, true
+			}
+			continue
+		}
 		if s.pred(v) {
 			return v, true
 		}
 	}
 }
 
+func (s *filterSource[T]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *filterSource[T]) Close() error { return closeSource(s.inner) }
+
 func (s *filterSource[T]) collectAll() []T {
+	if s.hooks.RecoverPanics {
+		return nil
+	}
 	if ss, ok := s.inner.(*sliceSource[T]); ok {
 		data := ss.remaining()
 		ss.idx = len(ss.data)
@@ -43,13 +77,18 @@ func (s *filterSource[T]) SizeHint() int {
 }
 
 type takeSource[T any] struct {
-	inner Source[T]
-	n     int
-	count int
+	inner     Source[T]
+	n         int
+	count     int
+	closeOnce sync.Once
+	closeErr  error
 }
 
 func (s *takeSource[T]) Next() (T, bool) {
 	if s.count >= s.n {
+		// Limit reached: release upstream goroutines and owned readers now
+		// rather than waiting for the consumer to close the pipeline.
+		s.Close()
 		var zero T
 		return zero, false
 	}
@@ -60,6 +99,13 @@ func (s *takeSource[T]) Next() (T, bool) {
 	return v, ok
 }
 
+func (s *takeSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *takeSource[T]) Close() error {
+	s.closeOnce.Do(func() { s.closeErr = closeSource(s.inner) })
+	return s.closeErr
+}
+
 func (s *takeSource[T]) SizeHint() int {
 	remaining := s.n - s.count
 	if remaining <= 0 {
@@ -92,6 +138,10 @@ func (s *skipSource[T]) Next() (T, bool) {
 	return s.inner.Next()
 }
 
+func (s *skipSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *skipSource[T]) Close() error { return closeSource(s.inner) }
+
 type peekSource[T any] struct {
 	inner Source[T]
 	fn    func(T)
@@ -105,6 +155,10 @@ func (s *peekSource[T]) Next() (T, bool) {
 	return v, ok
 }
 
+func (s *peekSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *peekSource[T]) Close() error { return closeSource(s.inner) }
+
 func (s *peekSource[T]) SizeHint() int {
 	if sizer, ok := s.inner.(Sizer); ok {
 		return sizer.SizeHint()
@@ -131,25 +185,52 @@ func (s *distinctSource[T]) Next() (T, bool) {
 	}
 }
 
+func (s *distinctSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *distinctSource[T]) Close() error { return closeSource(s.inner) }
+
 type mapSource[T any, U any] struct {
 	inner    Source[T]
 	fn       func(T) U
 	hooks    *Hooks[T]
 	hasHooks bool
+	err      errSlot
 }
 
 func (s *mapSource[T, U]) Next() (U, bool) {
-	v, ok := s.inner.Next()
-	if !ok {
-		var zero U
-		return zero, false
+	for {
+		v, ok := s.inner.Next()
+		if !ok {
+			var zero U
+			return zero, false
+		}
+		if s.hasHooks {
+			s.hooks.fireElement(v)
+		}
+		if !s.hooks.RecoverPanics {
+			return s.fn(v), true
+		}
+		result, perr, abort := callGuarded(s.hooks, s.fn, v)
+		if perr == nil {
+			return result, true
+		}
+		s.err.set(perr)
+		if abort {
+			var zero U
+			return zero, false
+		}
 	}
-	if s.hasHooks {
-		s.hooks.fireElement(v)
+}
+
+func (s *mapSource[T, U]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
 	}
-	return s.fn(v), true
+	return innerErr(s.inner)
 }
 
+func (s *mapSource[T, U]) Close() error { return closeSource(s.inner) }
+
 func (s *mapSource[T, U]) SizeHint() int {
 	if sizer, ok := s.inner.(Sizer); ok {
 		return sizer.SizeHint()
@@ -158,7 +239,7 @@ func (s *mapSource[T, U]) SizeHint() int {
 }
 
 func (s *mapSource[T, U]) collectAll() []U {
-	if ss, ok := s.inner.(*sliceSource[T]); ok && !s.hasHooks {
+	if ss, ok := s.inner.(*sliceSource[T]); ok && !s.hasHooks && !s.hooks.RecoverPanics {
 		data := ss.remaining()
 		ss.idx = len(ss.data)
 		result := make([]U, len(data))
@@ -177,6 +258,10 @@ type mapErrSource[T any, U any] struct {
 	hasHooks   bool
 	hasErr     bool
 	maxRetries int
+	// ctx is set by the ctx stages: once it is done, a failed call ends the
+	// stage instead of going to the error handler.
+	ctx context.Context
+	err errSlot
 }
 
 func (s *mapErrSource[T, U]) Next() (U, bool) {
@@ -190,14 +275,20 @@ func (s *mapErrSource[T, U]) Next() (U, bool) {
 			s.hooks.fireElement(v)
 		}
 
+		var err error
 		for attempt := 0; ; attempt++ {
 			if attempt >= s.maxRetries {
 				goto nextElem
 			}
-			result, err := s.fn(v)
+			var result U
+			result, err = s.call(v)
 			if err == nil {
 				return result, true
 			}
+			if s.ctx != nil && s.ctx.Err() != nil {
+				var zero U
+				return zero, false
+			}
 			if !s.hasErr {
 				goto nextElem
 			}
@@ -206,6 +297,7 @@ func (s *mapErrSource[T, U]) Next() (U, bool) {
 			case Skip:
 				goto nextElem
 			case Abort:
+				s.recordPanic(err)
 				var zero U
 				return zero, false
 			case Retry:
@@ -213,9 +305,45 @@ func (s *mapErrSource[T, U]) Next() (U, bool) {
 			}
 		}
 	nextElem:
+		s.recordPanic(err)
+	}
+}
+
+// recordPanic reports err by Err() if it is a recovered panic. It is called
+// only once an element is given up on, so a panic that a retry got past
+// does not fail the stream.
+func (s *mapErrSource[T, U]) recordPanic(err error) {
+	if perr, ok := err.(*PanicError); ok {
+		s.err.set(perr)
+	}
+}
+
+// call runs fn, converting a panic into a *PanicError when recovery is on.
+func (s *mapErrSource[T, U]) call(v T) (U, error) {
+	if !s.hooks.RecoverPanics {
+		return s.fn(v)
+	}
+	var fnErr error
+	result, perr := callRecover(func(v T) U {
+		r, err := s.fn(v)
+		fnErr = err
+		return r
+	}, v)
+	if perr != nil {
+		return result, perr
 	}
+	return result, fnErr
 }
 
+func (s *mapErrSource[T, U]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *mapErrSource[T, U]) Close() error { return closeSource(s.inner) }
+
 type flatMapSource[T any, U any] struct {
 	inner    Source[T]
 	fn       func(T) []U
@@ -223,6 +351,7 @@ type flatMapSource[T any, U any] struct {
 	hasHooks bool
 	buf      []U
 	idx      int
+	err      errSlot
 }
 
 func (s *flatMapSource[T, U]) Next() (U, bool) {
@@ -240,11 +369,93 @@ func (s *flatMapSource[T, U]) Next() (U, bool) {
 		if s.hasHooks {
 			s.hooks.fireElement(elem)
 		}
-		s.buf = s.fn(elem)
 		s.idx = 0
+		if !s.hooks.RecoverPanics {
+			s.buf = s.fn(elem)
+			continue
+		}
+		buf, perr, abort := callGuarded(s.hooks, s.fn, elem)
+		s.buf = buf
+		if perr != nil {
+			s.err.set(perr)
+			if abort {
+				var zero U
+				return zero, false
+			}
+		}
+	}
+}
+
+func (s *flatMapSource[T, U]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *flatMapSource[T, U]) Close() error { return closeSource(s.inner) }
+
+type scanSource[T any, S any, U any] struct {
+	inner    Source[T]
+	fn       func(S, T) (S, U)
+	state    *StageState[S]
+	hooks    *Hooks[T]
+	hasHooks bool
+	err      errSlot
+}
+
+func (s *scanSource[T, S, U]) Next() (U, bool) {
+	for {
+		v, ok := s.inner.Next()
+		if !ok {
+			var zero U
+			return zero, false
+		}
+		if s.hasHooks {
+			s.hooks.fireElement(v)
+		}
+		if !s.hooks.RecoverPanics {
+			return s.step(v), true
+		}
+		result, perr, abort := callGuarded(s.hooks, s.step, v)
+		if perr == nil {
+			return result, true
+		}
+		s.err.set(perr)
+		if abort {
+			var zero U
+			return zero, false
+		}
 	}
 }
 
+// step applies fn under the state lock. A panicking fn leaves the state unchanged.
+func (s *scanSource[T, S, U]) step(v T) U {
+	st := s.state
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	next, out := s.fn(st.value, v)
+	st.value = next
+	st.processed++
+	return out
+}
+
+func (s *scanSource[T, S, U]) SizeHint() int {
+	if sizer, ok := s.inner.(Sizer); ok {
+		return sizer.SizeHint()
+	}
+	return -1
+}
+
+func (s *scanSource[T, S, U]) Err() error {
+	if err := s.err.get(); err != nil {
+		return err
+	}
+	return innerErr(s.inner)
+}
+
+func (s *scanSource[T, S, U]) Close() error { return closeSource(s.inner) }
+
 type chunkSource[T any] struct {
 	inner    Source[T]
 	size     int
@@ -275,6 +486,10 @@ func (s *chunkSource[T]) Next() ([]T, bool) {
 	return chunk, true
 }
 
+func (s *chunkSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *chunkSource[T]) Close() error { return closeSource(s.inner) }
+
 func (s *chunkSource[T]) collectAll() [][]T {
 	if ss, ok := s.inner.(*sliceSource[T]); ok && !s.hasHooks {
 		data := ss.remaining()
@@ -361,6 +576,10 @@ func (s *windowSource[T]) Next() ([]T, bool) {
 	return out, true
 }
 
+func (s *windowSource[T]) Err() error { return innerErr(s.inner) }
+
+func (s *windowSource[T]) Close() error { return closeSource(s.inner) }
+
 func (s *windowSource[T]) collectAll() [][]T {
 	if ss, ok := s.inner.(*sliceSource[T]); ok && !s.hasHooks {
 		data := ss.remaining()
@@ -0,0 +1,50 @@
+package gosplice
+
+import "sync"
+
+// StageState holds the running state of a PipeMapWithState stage.
+// The stage updates it under a mutex, so Snapshot and Value are safe to
+// call from another goroutine while the pipeline runs — e.g. from a ticker
+// that writes periodic checkpoints.
+type StageState[S any] struct {
+	mu        sync.Mutex
+	value     S
+	processed int
+}
+
+// StateSnapshot is a consistent point-in-time copy of a StageState:
+// the state after Processed elements. Fields are exported so snapshots can
+// be persisted with encoding/json or encoding/gob.
+type StateSnapshot[S any] struct {
+	State     S
+	Processed int
+}
+
+func NewStageState[S any](init S) *StageState[S] {
+	return &StageState[S]{value: init}
+}
+
+// Value returns the current state.
+func (s *StageState[S]) Value() S {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value
+}
+
+// Snapshot returns the current state and the number of elements processed.
+// The state is copied by value: if S contains maps or slices that fn mutates
+// in place, return fresh values from fn instead so snapshots stay immutable.
+func (s *StageState[S]) Snapshot() StateSnapshot[S] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return StateSnapshot[S]{State: s.value, Processed: s.processed}
+}
+
+// Restore resets the state to a snapshot. To resume after a checkpoint,
+// restore the snapshot and Skip(snap.Processed) elements of the replayed input.
+func (s *StageState[S]) Restore(snap StateSnapshot[S]) {
+	s.mu.Lock()
+	s.value = snap.State
+	s.processed = snap.Processed
+	s.mu.Unlock()
+}
@@ -0,0 +1,68 @@
+package gosplice
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestStageStateSnapshotRestore(t *testing.T) {
+	st := NewStageState(0)
+	sum := func(s, n int) (int, int) { return s + n, s + n }
+
+	PipeMapWithState(FromSlice([]int{1, 2, 3}), st, sum).Collect()
+	snap := st.Snapshot()
+	if snap.State != 6 || snap.Processed != 3 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+
+	// Resume from the checkpoint over the replayed input.
+	resumed := NewStageState(-1)
+	resumed.Restore(snap)
+	got := PipeMapWithState(FromSlice([]int{1, 2, 3, 4, 5}).Skip(snap.Processed), resumed, sum).Collect()
+	assertSliceEqual(t, []int{10, 15}, got)
+	if resumed.Value() != 15 {
+		t.Errorf("expected 15, got %d", resumed.Value())
+	}
+}
+
+func TestStageStateSnapshotJSON(t *testing.T) {
+	st := NewStageState(map[string]int{"a": 1})
+	st.Restore(StateSnapshot[map[string]int]{State: map[string]int{"b": 2}, Processed: 7})
+	data, err := json.Marshal(st.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back StateSnapshot[map[string]int]
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Processed != 7 || back.State["b"] != 2 {
+		t.Errorf("round trip mismatch: %+v", back)
+	}
+}
+
+func TestStageStateConcurrentSnapshot(t *testing.T) {
+	st := NewStageState(0)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				snap := st.Snapshot()
+				if snap.State != snap.Processed {
+					t.Errorf("inconsistent snapshot %+v", snap)
+					return
+				}
+			}
+		}
+	}()
+	PipeMapWithState(FromRange(0, 10000), st, func(s, _ int) (int, int) { return s + 1, s }).Count()
+	close(stop)
+	wg.Wait()
+}
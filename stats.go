@@ -11,54 +11,25 @@ type Numeric interface {
 		~float32 | ~float64
 }
 
-type meanAcc struct {
-	sum   float64
-	count int
-}
-
-type welfordAcc struct {
-	count int
-	mean  float64
-	m2    float64
-}
-
-type correlationAcc struct {
-	n                 int
-	meanX, meanY      float64
-	m2X, m2Y, covarXY float64
-}
-
 // --- Pipeline aggregations ---
 
 func MeanBy[T any](p *Pipeline[T], fn func(T) float64) float64 {
 	defer p.finalize()
-	r := fold(p, meanAcc{}, func(a meanAcc, v T) meanAcc {
-		a.sum += fn(v)
-		a.count++
+	r := fold(p, MeanAcc{}, func(a MeanAcc, v T) MeanAcc {
+		a.Add(fn(v))
 		return a
 	})
-	if r.count == 0 {
-		return 0
-	}
-	return r.sum / float64(r.count)
+	return r.Result()
 }
 
 // VarianceBy uses Welford's online algorithm — single-pass, numerically stable.
 func VarianceBy[T any](p *Pipeline[T], fn func(T) float64) float64 {
 	defer p.finalize()
-	r := fold(p, welfordAcc{}, func(w welfordAcc, v T) welfordAcc {
-		w.count++
-		x := fn(v)
-		delta := x - w.mean
-		w.mean += delta / float64(w.count)
-		delta2 := x - w.mean
-		w.m2 += delta * delta2
+	r := fold(p, VarianceAcc{}, func(w VarianceAcc, v T) VarianceAcc {
+		w.Add(fn(v))
 		return w
 	})
-	if r.count < 1 {
-		return 0
-	}
-	return r.m2 / float64(r.count)
+	return r.Result()
 }
 
 func StdDevBy[T any](p *Pipeline[T], fn func(T) float64) float64 {
@@ -141,26 +112,11 @@ func (s Stats) IQR() float64 { return s.Q3 - s.Q1 }
 // Returns 0 for fewer than 2 elements or zero variance.
 func CorrelationBy[T any](p *Pipeline[T], fnX, fnY func(T) float64) float64 {
 	defer p.finalize()
-	r := fold(p, correlationAcc{}, func(s correlationAcc, v T) correlationAcc {
-		s.n++
-		x, y := fnX(v), fnY(v)
-		dx := x - s.meanX
-		s.meanX += dx / float64(s.n)
-		dx2 := x - s.meanX
-		s.m2X += dx * dx2
-
-		dy := y - s.meanY
-		s.meanY += dy / float64(s.n)
-		dy2 := y - s.meanY
-		s.m2Y += dy * dy2
-
-		s.covarXY += dx * (y - s.meanY)
+	r := fold(p, CorrelationAcc{}, func(s CorrelationAcc, v T) CorrelationAcc {
+		s.Add(fnX(v), fnY(v))
 		return s
 	})
-	if r.n < 2 || r.m2X == 0 || r.m2Y == 0 {
-		return 0
-	}
-	return r.covarXY / math.Sqrt(r.m2X*r.m2Y)
+	return r.Result()
 }
 
 // --- Standalone slice functions ---
@@ -0,0 +1,354 @@
+package gosplice
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Time-based stages
+// ---------------------------------------------------------------------------
+
+// Throttle passes the first element, then drops every element that arrives
+// within d of it; the next element after that opens a new interval. Unlike
+// RateLimit it never delays an element, so it suits live sources where
+// stale data is worthless. A non-positive d returns p unchanged.
+//
+// Throttle, Debounce, SampleEvery and Audit read the source on their own
+// goroutine, so "arrives" means when the source produces the element, not
+// when the consumer asks for it. They stop when the pipeline's context is
+// done; on normal end of input a pending element is emitted, not lost. The
+// context is the one set when the stage is added: attach it before, since
+// a WithContext call further down the chain is not seen by the stage.
+//
+//	clicks := gs.FromChannelCtx(ctx, events).Throttle(time.Second)
+func (p *Pipeline[T]) Throttle(d time.Duration) *Pipeline[T] {
+	return p.timed(d, func(time.Time) timeGate[T] { return &throttleGate[T]{d: d} })
+}
+
+// Debounce emits an element only after d has passed without another one,
+// dropping every element that is followed too soon. Bursts collapse into
+// their last element, e.g. a search box that queries after typing stops.
+//
+//	queries := gs.FromChannelCtx(ctx, keystrokes).Debounce(300 * time.Millisecond)
+func (p *Pipeline[T]) Debounce(d time.Duration) *Pipeline[T] {
+	return p.timed(d, func(time.Time) timeGate[T] { return &debounceGate[T]{d: d} })
+}
+
+// SampleEvery emits the latest element once every d, on a fixed tick that
+// starts with the stage. A tick with no new element since the previous one
+// emits nothing, so a slow source is not padded with duplicates.
+//
+//	gauge := gs.FromChannelCtx(ctx, readings).SampleEvery(time.Second)
+func (p *Pipeline[T]) SampleEvery(d time.Duration) *Pipeline[T] {
+	return p.timed(d, func(now time.Time) timeGate[T] { return &sampleGate[T]{d: d, next: now.Add(d)} })
+}
+
+// Audit waits d after an element arrives, then emits the latest element
+// seen in that window. Like Throttle it emits at most once per d, but it
+// keeps the last element of each burst instead of the first, and unlike
+// SampleEvery its window opens on arrival rather than on a fixed tick.
+func (p *Pipeline[T]) Audit(d time.Duration) *Pipeline[T] {
+	return p.timed(d, func(time.Time) timeGate[T] { return &auditGate[T]{d: d} })
+}
+
+// ---------------------------------------------------------------------------
+// Gates
+// ---------------------------------------------------------------------------
+
+// timeGate is the clock-independent core of a time-based stage: it is told
+// when elements arrive and when its deadline passes, and decides what to
+// emit. The stage's goroutine drives it on a timedClock.
+type timeGate[T any] interface {
+	// push handles an element arriving at now.
+	push(v T, now time.Time) (T, bool)
+	// fire handles the deadline passing; now is at or after it.
+	fire(now time.Time) (T, bool)
+	// deadline returns when fire is next due; false means no timer.
+	deadline() (time.Time, bool)
+	// flush returns the pending element, if any, at end of input.
+	flush() (T, bool)
+}
+
+type throttleGate[T any] struct {
+	d       time.Duration
+	next    time.Time
+	started bool
+}
+
+func (g *throttleGate[T]) push(v T, now time.Time) (T, bool) {
+	if g.started && now.Before(g.next) {
+		var zero T
+		return zero, false
+	}
+	g.started, g.next = true, now.Add(g.d)
+	return v, true
+}
+
+func (g *throttleGate[T]) fire(time.Time) (T, bool) {
+	var zero T
+	return zero, false
+}
+
+func (g *throttleGate[T]) deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (g *throttleGate[T]) flush() (T, bool) {
+	var zero T
+	return zero, false
+}
+
+// pending holds the element a gate emits later.
+type pending[T any] struct {
+	v   T
+	has bool
+}
+
+func (p *pending[T]) set(v T) { p.v, p.has = v, true }
+
+func (p *pending[T]) take() (T, bool) {
+	var zero T
+	v, ok := p.v, p.has
+	p.v, p.has = zero, false
+	return v, ok
+}
+
+type debounceGate[T any] struct {
+	d    time.Duration
+	due  time.Time
+	last pending[T]
+}
+
+func (g *debounceGate[T]) push(v T, now time.Time) (T, bool) {
+	g.last.set(v)
+	g.due = now.Add(g.d)
+	var zero T
+	return zero, false
+}
+
+func (g *debounceGate[T]) fire(now time.Time) (T, bool) {
+	if now.Before(g.due) {
+		var zero T
+		return zero, false
+	}
+	return g.last.take()
+}
+
+func (g *debounceGate[T]) deadline() (time.Time, bool) { return g.due, g.last.has }
+
+func (g *debounceGate[T]) flush() (T, bool) { return g.last.take() }
+
+type sampleGate[T any] struct {
+	d      time.Duration
+	next   time.Time
+	latest pending[T]
+}
+
+func (g *sampleGate[T]) push(v T, _ time.Time) (T, bool) {
+	g.latest.set(v)
+	var zero T
+	return zero, false
+}
+
+func (g *sampleGate[T]) fire(now time.Time) (T, bool) {
+	if now.Before(g.next) {
+		var zero T
+		return zero, false
+	}
+	// Skip ticks missed while the consumer was blocking the stage.
+	g.next = g.next.Add((now.Sub(g.next)/g.d + 1) * g.d)
+	return g.latest.take()
+}
+
+func (g *sampleGate[T]) deadline() (time.Time, bool) { return g.next, true }
+
+func (g *sampleGate[T]) flush() (T, bool) { return g.latest.take() }
+
+type auditGate[T any] struct {
+	d      time.Duration
+	due    time.Time
+	latest pending[T]
+}
+
+func (g *auditGate[T]) push(v T, now time.Time) (T, bool) {
+	if !g.latest.has {
+		g.due = now.Add(g.d)
+	}
+	g.latest.set(v)
+	var zero T
+	return zero, false
+}
+
+func (g *auditGate[T]) fire(now time.Time) (T, bool) {
+	if now.Before(g.due) {
+		var zero T
+		return zero, false
+	}
+	return g.latest.take()
+}
+
+func (g *auditGate[T]) deadline() (time.Time, bool) { return g.due, g.latest.has }
+
+func (g *auditGate[T]) flush() (T, bool) { return g.latest.take() }
+
+// ---------------------------------------------------------------------------
+// Driver
+// ---------------------------------------------------------------------------
+
+// timedClock is the time source of the time-based stages. The stage
+// calls wait before every select, so a test clock can also use it to step
+// the stage one event at a time.
+type timedClock interface {
+	now() time.Time
+	// wait returns a channel that receives once due has passed, or nil
+	// when ok is false (no deadline).
+	wait(due time.Time, ok bool) <-chan time.Time
+	stop()
+}
+
+// realClock is the timedClock on wall time, with one reusable timer.
+type realClock struct {
+	timer *time.Timer
+}
+
+func newRealClock() *realClock {
+	t := time.NewTimer(time.Hour)
+	t.Stop()
+	return &realClock{timer: t}
+}
+
+func (c *realClock) now() time.Time { return time.Now() }
+
+func (c *realClock) wait(due time.Time, ok bool) <-chan time.Time {
+	if !ok {
+		return nil
+	}
+	c.timer.Reset(time.Until(due))
+	return c.timer.C
+}
+
+func (c *realClock) stop() { c.timer.Stop() }
+
+func (p *Pipeline[T]) timed(d time.Duration, newGate func(now time.Time) timeGate[T]) *Pipeline[T] {
+	if d <= 0 {
+		return p
+	}
+	return p.timedOn(newRealClock(), newGate)
+}
+
+// timedOn runs a time-based stage on clock.
+func (p *Pipeline[T]) timedOn(clock timedClock, newGate func(now time.Time) timeGate[T]) *Pipeline[T] {
+	src := p.source
+	outCh := make(chan T)
+	errs := &errSlot{}
+	closed := make(chan struct{})
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	go func() {
+		defer close(outCh)
+
+		itemCh := make(chan T)
+		go func() {
+			defer close(itemCh)
+			for {
+				v, ok, perr := nextRecover(src)
+				if perr != nil {
+					errs.set(perr)
+					return
+				}
+				if !ok {
+					errs.set(innerErr(src))
+					return
+				}
+				select {
+				case itemCh <- v:
+				case <-ctx.Done():
+					return
+				case <-closed:
+					return
+				}
+			}
+		}()
+
+		send := func(v T) bool {
+			select {
+			case outCh <- v:
+				return true
+			case <-ctx.Done():
+				return false
+			case <-closed:
+				return false
+			}
+		}
+
+		gate := newGate(clock.now())
+		defer clock.stop()
+		for {
+			timerC := clock.wait(gate.deadline())
+
+			select {
+			case <-closed:
+				return
+			case <-ctx.Done():
+				return
+			case v, ok := <-itemCh:
+				if !ok {
+					if v, ok := gate.flush(); ok {
+						send(v)
+					}
+					return
+				}
+				if out, ok := gate.push(v, clock.now()); ok && !send(out) {
+					return
+				}
+			case now := <-timerC:
+				if out, ok := gate.fire(now); ok && !send(out) {
+					return
+				}
+			}
+		}
+	}()
+
+	ts := &timedSource[T]{chanSource: chanSource[T]{ch: outCh}, errs: errs, closed: closed, inner: src}
+	stop := p.stop
+	return &Pipeline[T]{
+		source: ts,
+		hooks:  p.hooks,
+		ctx:    p.ctx,
+		cancel: p.cancel,
+		stop: func() {
+			ts.stop()
+			if stop != nil {
+				stop()
+			}
+		},
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+// timedSource yields the elements emitted by a time-based stage's goroutine
+// and reports a panic recovered while reading the upstream source, or else
+// the upstream source's error, recorded by the reader once the source is
+// exhausted.
+type timedSource[T any] struct {
+	chanSource[T]
+	errs   *errSlot
+	closed chan struct{}
+	once   sync.Once
+	inner  any
+}
+
+func (s *timedSource[T]) Err() error { return s.errs.get() }
+
+func (s *timedSource[T]) stop() {
+	s.once.Do(func() { close(s.closed) })
+}
+
+// Close stops the stage's goroutines and closes the upstream source.
+func (s *timedSource[T]) Close() error {
+	s.stop()
+	return closeSource(s.inner)
+}
@@ -0,0 +1,238 @@
+package gosplice
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// at is an element arriving at an offset from the start of a replay.
+type at struct {
+	t time.Duration
+	v int
+}
+
+// stamped is an element emitted at an offset from the start of a replay.
+type stamped struct {
+	t time.Duration
+	v int
+}
+
+// stepClock is a timedClock the test advances by hand. The stage reports
+// on idle each time it is about to wait, so the test can step it one event
+// at a time.
+type stepClock struct {
+	t     time.Time
+	due   time.Time
+	armed bool
+	c     chan time.Time
+	idle  chan struct{}
+}
+
+func (c *stepClock) now() time.Time { return c.t }
+
+func (c *stepClock) wait(due time.Time, ok bool) <-chan time.Time {
+	c.due, c.armed = due, ok
+	c.idle <- struct{}{}
+	if !ok {
+		return nil
+	}
+	return c.c
+}
+
+func (c *stepClock) stop() {}
+
+// replayGate runs the stage built by g on a stepClock: elements arrive on
+// a channel at their offsets, deadlines that fall before the next arrival
+// fire first, then the input ends at end and the pending element is
+// flushed.
+func replayGate(g func(now time.Time) timeGate[int], in []at, end time.Duration) []stamped {
+	clk := &stepClock{t: time.Unix(0, 0), c: make(chan time.Time), idle: make(chan struct{})}
+	start := clk.t
+	ch := make(chan int)
+	out := FromChannel(ch).timedOn(clk, g).source.(*timedSource[int]).ch
+	var got []stamped
+	// settle collects what the stage emits until it waits again, or until
+	// it is done.
+	settle := func() {
+		for {
+			select {
+			case v, ok := <-out:
+				if !ok {
+					return
+				}
+				got = append(got, stamped{clk.t.Sub(start), v})
+			case <-clk.idle:
+				return
+			}
+		}
+	}
+	runUntil := func(t time.Time) {
+		for clk.armed && !clk.due.After(t) {
+			clk.t = clk.due
+			clk.c <- clk.due
+			settle()
+		}
+		clk.t = t
+	}
+	settle()
+	for _, e := range in {
+		runUntil(start.Add(e.t))
+		ch <- e.v
+		settle()
+	}
+	runUntil(start.Add(end))
+	close(ch)
+	settle()
+	return got
+}
+
+const ms = time.Millisecond
+
+// burst: 1-3 close together, a gap, 4-5 close together, a long gap, 6.
+var burst = []at{{0, 1}, {10 * ms, 2}, {20 * ms, 3}, {150 * ms, 4}, {160 * ms, 5}, {400 * ms, 6}}
+
+func TestThrottle_KeepsFirstPerInterval(t *testing.T) {
+	got := replayGate(func(time.Time) timeGate[int] { return &throttleGate[int]{d: 100 * ms} }, burst, time.Second)
+	want := []stamped{{0, 1}, {150 * ms, 4}, {400 * ms, 6}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestThrottle_IntervalOpensOnEmit(t *testing.T) {
+	// 2 is dropped at 90ms, but does not extend the interval: 3 passes.
+	in := []at{{0, 1}, {90 * ms, 2}, {100 * ms, 3}, {150 * ms, 4}}
+	got := replayGate(func(time.Time) timeGate[int] { return &throttleGate[int]{d: 100 * ms} }, in, time.Second)
+	want := []stamped{{0, 1}, {100 * ms, 3}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDebounce_EmitsLastAfterQuiet(t *testing.T) {
+	got := replayGate(func(time.Time) timeGate[int] { return &debounceGate[int]{d: 50 * ms} }, burst, time.Second)
+	want := []stamped{{70 * ms, 3}, {210 * ms, 5}, {450 * ms, 6}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDebounce_FlushesPendingAtEnd(t *testing.T) {
+	got := replayGate(func(time.Time) timeGate[int] { return &debounceGate[int]{d: 50 * ms} }, burst, 420*ms)
+	want := []stamped{{70 * ms, 3}, {210 * ms, 5}, {420 * ms, 6}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSampleEvery_LatestPerTick(t *testing.T) {
+	newGate := func(now time.Time) timeGate[int] { return &sampleGate[int]{d: 100 * ms, next: now.Add(100 * ms)} }
+	got := replayGate(newGate, burst, time.Second)
+	// Ticks at 100, 200, 300...: nothing new at 300, so nothing emitted.
+	want := []stamped{{100 * ms, 3}, {200 * ms, 5}, {500 * ms, 6}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSampleEvery_SkipsMissedTicks(t *testing.T) {
+	g := &sampleGate[int]{d: 100 * ms, next: time.Unix(0, 0).Add(100 * ms)}
+	g.push(1, time.Unix(0, 0))
+	// The stage was blocked until 350ms: one emission, next tick at 400ms.
+	if v, ok := g.fire(time.Unix(0, 0).Add(350 * ms)); !ok || v != 1 {
+		t.Fatalf("fire: %v %v", v, ok)
+	}
+	if due, _ := g.deadline(); !due.Equal(time.Unix(0, 0).Add(400 * ms)) {
+		t.Errorf("next tick %v", due.Sub(time.Unix(0, 0)))
+	}
+}
+
+func TestAudit_LatestPerWindowFromArrival(t *testing.T) {
+	got := replayGate(func(time.Time) timeGate[int] { return &auditGate[int]{d: 100 * ms} }, burst, time.Second)
+	want := []stamped{{100 * ms, 3}, {250 * ms, 5}, {500 * ms, 6}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTimed_EndToEnd(t *testing.T) {
+	// A slice arrives all at once: Throttle keeps the first element,
+	// Debounce, SampleEvery and Audit the last.
+	in := []int{1, 2, 3, 4, 5}
+	if got := FromSlice(in).Throttle(time.Hour).Collect(); !slices.Equal(got, []int{1}) {
+		t.Errorf("Throttle: %v", got)
+	}
+	if got := FromSlice(in).Debounce(time.Hour).Collect(); !slices.Equal(got, []int{5}) {
+		t.Errorf("Debounce: %v", got)
+	}
+	if got := FromSlice(in).SampleEvery(time.Hour).Collect(); !slices.Equal(got, []int{5}) {
+		t.Errorf("SampleEvery: %v", got)
+	}
+	if got := FromSlice(in).Audit(time.Hour).Collect(); !slices.Equal(got, []int{5}) {
+		t.Errorf("Audit: %v", got)
+	}
+	if got := FromSlice(in).Debounce(0).Collect(); !slices.Equal(got, in) {
+		t.Errorf("Debounce(0): %v", got)
+	}
+}
+
+func TestTimed_WithContextAfter(t *testing.T) {
+	// WithContext on the stage's pipeline must not stop its goroutine.
+	in := []int{1, 2, 3, 4, 5}
+	stages := map[string]func(*Pipeline[int]) *Pipeline[int]{
+		"Throttle":    func(p *Pipeline[int]) *Pipeline[int] { return p.Throttle(time.Hour) },
+		"Debounce":    func(p *Pipeline[int]) *Pipeline[int] { return p.Debounce(time.Hour) },
+		"SampleEvery": func(p *Pipeline[int]) *Pipeline[int] { return p.SampleEvery(time.Hour) },
+		"Audit":       func(p *Pipeline[int]) *Pipeline[int] { return p.Audit(time.Hour) },
+	}
+	for name, stage := range stages {
+		if got := stage(FromSlice(in)).WithContext(context.Background()).Collect(); len(got) != 1 {
+			t.Errorf("%s: got %v", name, got)
+		}
+	}
+}
+
+func TestTimed_DebounceLiveChannel(t *testing.T) {
+	ch := make(chan int)
+	out := FromChannel(ch).Debounce(20 * ms)
+	go func() {
+		ch <- 1
+		ch <- 2
+		time.Sleep(100 * ms)
+		ch <- 3
+		close(ch)
+	}()
+	if got := out.Collect(); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestTimed_StopsOnContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*ms)
+	defer cancel()
+	ch := make(chan int) // never closed
+	p := FromChannelCtx(ctx, ch).WithContext(ctx).SampleEvery(5 * ms)
+	done := make(chan []int)
+	go func() { done <- p.Collect() }()
+	select {
+	case got := <-done:
+		if len(got) != 0 {
+			t.Errorf("got %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stage did not stop on context")
+	}
+}
+
+func TestTimed_ForwardsSourceErr(t *testing.T) {
+	p := FromSource[int](&errAfterSource{n: 3}).Debounce(time.Hour)
+	if got := p.Collect(); !slices.Equal(got, []int{0}) {
+		t.Errorf("got %v", got)
+	}
+	if p.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("expected ErrUnexpectedEOF through Debounce, got %v", p.Err())
+	}
+}
@@ -6,9 +6,10 @@ func PipeMap[T any, U any](p *Pipeline[T], fn func(T) U) *Pipeline[U] {
 			inner: p.source, fn: fn,
 			hooks: p.hooks, hasHooks: p.hooks.hasElement(),
 		},
-		hooks:   newHooks[U](),
+		hooks:   inheritHooks[U](p.hooks),
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -23,9 +24,10 @@ func PipeMapErr[T any, U any](p *Pipeline[T], fn func(T) (U, error)) *Pipeline[U
 			hooks: p.hooks, hasHooks: p.hooks.hasElement(),
 			hasErr: p.hooks.hasError(), maxRetries: p.hooks.MaxRetries,
 		},
-		hooks:   newHooks[U](),
+		hooks:   inheritHooks[U](p.hooks),
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -36,9 +38,50 @@ func PipeFlatMap[T any, U any](p *Pipeline[T], fn func(T) []U) *Pipeline[U] {
 			inner: p.source, fn: fn,
 			hooks: p.hooks, hasHooks: p.hooks.hasElement(),
 		},
-		hooks:   newHooks[U](),
+		hooks:   inheritHooks[U](p.hooks),
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
+		ctxNoop: p.ctxNoop,
+	}
+}
+
+// PipeScan threads a state through the stream: for each element fn receives
+// the current state and returns the next state plus the value to emit.
+// Use it for running totals, sequence numbers and other running computations
+// instead of closures capturing mutable variables.
+//
+//	running := gs.PipeScan(orders, 0.0, func(total float64, o Order) (float64, float64) {
+//	    total += o.Amount
+//	    return total, total
+//	})
+func PipeScan[T any, S any, U any](p *Pipeline[T], init S, fn func(S, T) (S, U)) *Pipeline[U] {
+	return PipeMapWithState(p, NewStageState(init), fn)
+}
+
+// PipeMapIndexed transforms T→U, passing the 0-based position of each element.
+func PipeMapIndexed[T any, U any](p *Pipeline[T], fn func(int, T) U) *Pipeline[U] {
+	return PipeScan(p, 0, func(i int, v T) (int, U) {
+		return i + 1, fn(i, v)
+	})
+}
+
+// PipeMapWithState is PipeScan with caller-owned state. The state is updated
+// under a lock, so st.Snapshot can be taken concurrently for checkpointing;
+// restore it with st.Restore before re-running to resume.
+//
+// The stage itself is sequential. When combined with parallel stages, put it
+// before or after them rather than inside a worker function.
+func PipeMapWithState[T any, S any, U any](p *Pipeline[T], st *StageState[S], fn func(S, T) (S, U)) *Pipeline[U] {
+	return &Pipeline[U]{
+		source: &scanSource[T, S, U]{
+			inner: p.source, fn: fn, state: st,
+			hooks: p.hooks, hasHooks: p.hooks.hasElement(),
+		},
+		hooks:   inheritHooks[U](p.hooks),
+		ctx:     p.ctx,
+		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -49,6 +92,7 @@ func PipeDistinct[T comparable](p *Pipeline[T]) *Pipeline[T] {
 		hooks:   p.hooks,
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -59,9 +103,10 @@ func PipeChunk[T any](p *Pipeline[T], size int) *Pipeline[[]T] {
 			inner: p.source, size: size,
 			hooks: p.hooks, hasHooks: p.hooks.hasElement(),
 		},
-		hooks:   newHooks[[]T](),
+		hooks:   inheritHooks[[]T](p.hooks),
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -77,9 +122,10 @@ func PipeWindow[T any](p *Pipeline[T], size, step int) *Pipeline[[]T] {
 			inner: p.source, size: size, step: step,
 			hooks: p.hooks, hasHooks: p.hooks.hasElement(),
 		},
-		hooks:   newHooks[[]T](),
+		hooks:   inheritHooks[[]T](p.hooks),
 		ctx:     p.ctx,
 		cancel:  p.cancel,
+		stop:    p.stop,
 		ctxNoop: p.ctxNoop,
 	}
 }
@@ -2,6 +2,7 @@ package gosplice
 
 import (
 	"context"
+	"fmt"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -121,3 +122,74 @@ func TestChainingPipeMapPipeFlatMap(t *testing.T) {
 				func(n int) int { return n * 2 }),
 			func(n int) []int { return []int{n, n + 1} }).Collect())
 }
+
+// ===========================================================================
+// PipeScan / PipeMapIndexed / PipeMapWithState
+// ===========================================================================
+
+func TestPipeScanRunningTotal(t *testing.T) {
+	got := PipeScan(FromSlice([]int{1, 2, 3, 4}), 0, func(sum, n int) (int, int) {
+		sum += n
+		return sum, sum
+	}).Collect()
+	assertSliceEqual(t, []int{1, 3, 6, 10}, got)
+}
+
+func TestPipeScanDifferentOutputType(t *testing.T) {
+	got := PipeScan(FromSlice([]string{"a", "b", "a", "c", "b"}), map[string]bool{},
+		func(seen map[string]bool, s string) (map[string]bool, bool) {
+			dup := seen[s]
+			seen[s] = true
+			return seen, dup
+		}).Collect()
+	assertSliceEqual(t, []bool{false, false, true, false, true}, got)
+}
+
+func TestPipeScanEmpty(t *testing.T) {
+	if got := PipeScan(FromSlice([]int{}), 0, func(s, n int) (int, int) { return s, n }).Collect(); len(got) != 0 {
+		t.Errorf("expected empty, got %v", got)
+	}
+}
+
+func TestPipeMapIndexed(t *testing.T) {
+	got := PipeMapIndexed(FromSlice([]string{"a", "b", "c"}).Skip(1), func(i int, s string) string {
+		return fmt.Sprintf("%d:%s", i, s)
+	}).Collect()
+	assertSliceEqual(t, []string{"0:b", "1:c"}, got)
+}
+
+func TestPipeScanFiresElementHooks(t *testing.T) {
+	var count atomic.Int64
+	PipeMapIndexed(FromSlice([]int{5, 6, 7}).WithElementHook(CountElements[int](&count)),
+		func(i, n int) int { return i * n }).Collect()
+	if count.Load() != 3 {
+		t.Errorf("expected 3 element hooks, got %d", count.Load())
+	}
+}
+
+func TestPipeScanPanicLeavesStateUnchanged(t *testing.T) {
+	st := NewStageState(0)
+	got := PipeMapWithState(FromSlice([]int{1, 2, 3}).WithPanicRecovery(), st, func(sum, n int) (int, int) {
+		if n == 2 {
+			panic("two")
+		}
+		return sum + n, sum + n
+	}).Collect()
+	assertSliceEqual(t, []int{1, 4}, got)
+	if snap := st.Snapshot(); snap.State != 4 || snap.Processed != 2 {
+		t.Errorf("expected state 4 after 2 elements, got %+v", snap)
+	}
+}
+
+func TestPipeScanStopsWithCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	got := PipeScan(FromRange(0, 1000).WithContext(ctx), 0, func(s, n int) (int, int) {
+		if n == 10 {
+			cancel()
+		}
+		return s + n, s + n
+	}).Collect()
+	if len(got) > 12 {
+		t.Errorf("expected early stop, got %d elements", len(got))
+	}
+}